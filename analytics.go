@@ -1,6 +1,7 @@
 package analytics
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -37,6 +38,45 @@ type Client interface {
 	// happens if the client was already closed at the time the method was
 	// called or if the message was malformed.
 	Enqueue(Message) error
+
+	// Queues a message like `Enqueue`, but gives up waiting for room in the
+	// queue when the context is canceled, in which case the context error is
+	// returned.
+	// The message gets the identity and analytics context carried by the
+	// context, see `WithIdentity`, `WithAnalyticsContext` and `Enrich`.
+	// How long the method may block also depends on the overflow policy set in
+	// the client configuration.
+	EnqueueContext(context.Context, Message) error
+
+	// Sends all messages queued by the client without waiting for the batching
+	// rules to be met, then waits until the batches have been sent or the
+	// context is canceled.
+	// This is useful in programs that need to make sure messages have been
+	// delivered while keeping the client open, for example at the end of a
+	// serverless function invocation.
+	//
+	// Batches that were still being sent when an earlier call to `Flush` gave
+	// up are waited for as well.
+	//
+	// The method returns an error if the client was closed or if the context
+	// was canceled before the batches were sent. Delivery failures are still
+	// reported through the callback.
+	Flush(context.Context) error
+
+	// Closes the client like `Close`, but keeps retrying to send the queued
+	// messages until they are delivered or the context is canceled.
+	// When the context is canceled in-flight requests are aborted and the
+	// messages that weren't sent yet are reported to the callback with
+	// `ErrAbandoned`.
+	//
+	// The method returns a `CloseError` summarizing how many messages were
+	// delivered, failed or abandoned during the shutdown if any of them
+	// could not be sent, or `ErrClosed` if the client was already closed.
+	CloseContext(context.Context) error
+
+	// Returns a snapshot of the statistics maintained by the client about the
+	// messages it processed, see `Stats` for details.
+	Stats() Stats
 }
 
 type client struct {
@@ -46,7 +86,7 @@ type client struct {
 	// This channel is where the `Enqueue` method writes messages so they can be
 	// picked up and pushed by the backend goroutine taking care of applying the
 	// batching rules.
-	msgs chan Message
+	msgs chan message
 
 	// These two channels are used to synchronize the client shutting down when
 	// `Close` is called.
@@ -56,6 +96,29 @@ type client struct {
 	quit     chan struct{}
 	shutdown chan struct{}
 
+	// This channel is closed to signal the goroutines sending batches that they
+	// must stop retrying. `Close` closes it right away while `CloseContext`
+	// only closes it when its context is canceled.
+	abort chan struct{}
+
+	// This context is canceled when `CloseContext` gives up on sending the
+	// queued messages, it aborts the HTTP requests that are in-flight.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// The time until which uploads are paused because the API responded with a
+	// `Retry-After` header.
+	pause pause
+
+	// Counters of the messages processed by the client, they are exposed by
+	// the `Stats` method.
+	counters *counters
+
+	// This channel is used by `Flush` to ask the backend goroutine to send
+	// the queued messages. The backend goroutine closes the channel it receives
+	// once all batches sent up to that point are done.
+	flushes chan chan struct{}
+
 	// This HTTP client is used to send requests to the backend, it uses the
 	// HTTP transport provided in the configuration.
 	http http.Client
@@ -80,16 +143,36 @@ func NewWithConfig(writeKey string, config Config) (cli Client, err error) {
 		return
 	}
 
+	config = makeConfig(config)
+
 	c := &client{
-		Config:   makeConfig(config),
+		Config:   config,
 		key:      writeKey,
-		msgs:     make(chan Message, 100),
+		msgs:     make(chan message, config.QueueSize),
 		quit:     make(chan struct{}),
 		shutdown: make(chan struct{}),
+		abort:    make(chan struct{}),
+		flushes:  make(chan chan struct{}),
+		counters: newCounters(),
 		http:     makeHttpClient(config.Transport),
 	}
 
-	go c.loop()
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	var replay []message
+
+	if c.Storage != nil {
+		if replay, err = c.replay(); err != nil {
+			// The client owns the storage and the dead-letter sink, they are
+			// released since the client will never be returned.
+			c.cancel()
+			c.closeStorage()
+			c.closeDeadLetter()
+			return
+		}
+	}
+
+	go c.loop(replay)
 
 	cli = c
 	return
@@ -105,6 +188,86 @@ func makeHttpClient(transport http.RoundTripper) http.Client {
 	return httpClient
 }
 
+// Prepare returns a copy of msg with the defaults that clients set on the
+// messages they queue, then validates it. The type of the message is always
+// set, a message id and the current time are only set if the message didn't
+// have them already. Pointers to messages are dereferenced.
+//
+// The function returns an error if the message is invalid or has a custom
+// type, like `Enqueue` would. It is mostly useful to implement the `Client`
+// interface with the same behavior as the clients of this package, for
+// example in tests.
+func Prepare(msg Message) (Message, error) {
+	return prepareMessage(msg, uid(), time.Now())
+}
+
+// Stamps and validates the message passed as argument.
+func prepareMessage(msg Message, id string, ts time.Time) (Message, error) {
+	msg, err := stampMessage(msg, id, ts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := msg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// Sets the type, message id and timestamp of the message passed as argument, the
+// id and timestamp are only set if they weren't already.
+// The function returns an error if the message has a custom type.
+func stampMessage(msg Message, id string, ts time.Time) (Message, error) {
+	msg = dereferenceMessage(msg)
+
+	switch m := msg.(type) {
+	case Alias:
+		m.Type = "alias"
+		m.MessageId = makeMessageId(m.MessageId, id)
+		m.Timestamp = makeTimestamp(m.Timestamp, ts)
+		return m, nil
+
+	case Group:
+		m.Type = "group"
+		m.MessageId = makeMessageId(m.MessageId, id)
+		m.Timestamp = makeTimestamp(m.Timestamp, ts)
+		return m, nil
+
+	case Identify:
+		m.Type = "identify"
+		m.MessageId = makeMessageId(m.MessageId, id)
+		m.Timestamp = makeTimestamp(m.Timestamp, ts)
+		return m, nil
+
+	case Page:
+		m.Type = "page"
+		m.MessageId = makeMessageId(m.MessageId, id)
+		m.Timestamp = makeTimestamp(m.Timestamp, ts)
+		return m, nil
+
+	case Screen:
+		m.Type = "screen"
+		m.MessageId = makeMessageId(m.MessageId, id)
+		m.Timestamp = makeTimestamp(m.Timestamp, ts)
+		return m, nil
+
+	case Track:
+		m.Type = "track"
+		m.MessageId = makeMessageId(m.MessageId, id)
+		m.Timestamp = makeTimestamp(m.Timestamp, ts)
+		return m, nil
+	}
+
+	// Invalid messages are reported with their validation error, which is
+	// more useful to the application than the message type.
+	if err := msg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("messages with custom types cannot be enqueued: %T", msg)
+}
+
 func dereferenceMessage(msg Message) Message {
 	switch m := msg.(type) {
 	case *Alias:
@@ -142,55 +305,86 @@ func dereferenceMessage(msg Message) Message {
 	return msg
 }
 
-func (c *client) Enqueue(msg Message) (err error) {
-	msg = dereferenceMessage(msg)
-	if err = msg.Validate(); err != nil {
-		return
-	}
+func (c *client) Enqueue(msg Message) error {
+	return c.EnqueueContext(context.Background(), msg)
+}
 
+func (c *client) EnqueueContext(ctx context.Context, msg Message) (err error) {
 	var id = c.uid()
 	var ts = c.now()
 
-	switch m := msg.(type) {
-	case Alias:
-		m.Type = "alias"
-		m.MessageId = makeMessageId(m.MessageId, id)
-		m.Timestamp = makeTimestamp(m.Timestamp, ts)
-		msg = m
+	msg = Enrich(ctx, msg)
 
-	case Group:
-		m.Type = "group"
-		m.MessageId = makeMessageId(m.MessageId, id)
-		m.Timestamp = makeTimestamp(m.Timestamp, ts)
-		msg = m
+	if len(c.Middleware) != 0 {
+		if msg, err = stampMessage(msg, id, ts); err != nil {
+			return
+		}
 
-	case Identify:
-		m.Type = "identify"
-		m.MessageId = makeMessageId(m.MessageId, id)
-		m.Timestamp = makeTimestamp(m.Timestamp, ts)
-		msg = m
+		var queued = msg
+		var reason error
 
-	case Page:
-		m.Type = "page"
-		m.MessageId = makeMessageId(m.MessageId, id)
-		m.Timestamp = makeTimestamp(m.Timestamp, ts)
-		msg = m
+		if msg, reason = c.applyMiddleware(msg); msg == nil {
+			err = DroppedError{Reason: reason}
+			c.debug("message dropped by middleware", Field{"message", queued}, Field{"error", err})
+			c.notifyFailure([]message{{msg: queued}}, err)
+			return nil
+		}
+	}
 
-	case Screen:
-		m.Type = "screen"
-		m.MessageId = makeMessageId(m.MessageId, id)
-		m.Timestamp = makeTimestamp(m.Timestamp, ts)
-		msg = m
+	// Middleware may have replaced the message with one that doesn't have the
+	// defaults set, they are applied again before validating it.
+	if msg, err = prepareMessage(msg, id, ts); err != nil {
+		return
+	}
 
-	case Track:
-		m.Type = "track"
-		m.MessageId = makeMessageId(m.MessageId, id)
-		m.Timestamp = makeTimestamp(m.Timestamp, ts)
-		msg = m
+	return c.enqueue(ctx, message{msg: msg})
+}
 
-	default:
-		err = fmt.Errorf("messages with custom types cannot be enqueued: %T", msg)
-		return
+// Queues a message that was already serialized, the JSON representation is
+// sent as-is and msg is only used to report the outcome to the callback.
+// Unlike `EnqueueContext` the message doesn't go through the middleware and
+// doesn't get the defaults, identity or analytics context carried by ctx.
+func (c *client) enqueueRaw(ctx context.Context, msg Message, b []byte) error {
+	if len(b) > maxMessageBytes {
+		return ErrMessageTooBig
+	}
+	return c.enqueue(ctx, message{msg: msg, json: b})
+}
+
+// Writes the message to the storage (if any) and to the queue picked up by the
+// backend goroutine.
+func (c *client) enqueue(ctx context.Context, m message) (err error) {
+	if c.Storage != nil {
+		select {
+		case <-c.quit:
+			// The storage may already have been closed by the backend
+			// goroutine, there is no point in writing to it.
+			err = ErrClosed
+			return
+		default:
+		}
+
+		// The message is written to the storage before being queued so it can
+		// be recovered if the program dies before the message was sent.
+		// Messages that can't be serialized are passed down as-is, the backend
+		// goroutine reports them to the callback like it does when there is no
+		// storage.
+		if m.json == nil {
+			if s, e := makeMessage(m.msg, maxMessageBytes); e == nil {
+				m = s
+			}
+		}
+
+		if m.json != nil {
+			if m.seq, err = c.Storage.Append(m.json); err != nil {
+				// The client may have been closed after the check above,
+				// which is reported the same way.
+				if err == errStorageClosed {
+					err = ErrClosed
+				}
+				return
+			}
+		}
 	}
 
 	defer func() {
@@ -201,14 +395,58 @@ func (c *client) Enqueue(msg Message) (err error) {
 		if recover() != nil {
 			err = ErrClosed
 		}
+
+		// Messages that didn't make it to the queue are not retained in the
+		// storage, the caller was notified with an error.
+		if err != nil {
+			c.ack([]message{m})
+		}
 	}()
 
-	c.msgs <- msg
+	if err = c.queue(ctx, m); err == nil {
+		c.counters.add(&c.counters.enqueued, 1)
+	}
 	return
 }
 
 // Close and flush metrics.
 func (c *client) Close() (err error) {
+	if err = c.stop(); err != nil {
+		return
+	}
+	close(c.abort)
+	<-c.shutdown
+	return
+}
+
+func (c *client) CloseContext(ctx context.Context) (err error) {
+	start := c.counters.snapshot()
+
+	if err = c.stop(); err != nil {
+		return
+	}
+
+	select {
+	case <-c.shutdown:
+	case <-ctx.Done():
+		c.debug("close deadline exceeded, abandoning messages")
+		c.cancel()
+		close(c.abort)
+		<-c.shutdown
+	}
+
+	if e := c.counters.since(start); e.Failed != 0 || e.Abandoned != 0 {
+		err = e
+	}
+	return
+}
+
+func (c *client) Stats() Stats {
+	return c.counters.stats(len(c.msgs))
+}
+
+// Signals the backend goroutine that it has to stop.
+func (c *client) stop() (err error) {
 	defer func() {
 		// Always recover, a panic could be raised if `c`.quit was closed which
 		// means the method was called more than once.
@@ -217,119 +455,295 @@ func (c *client) Close() (err error) {
 		}
 	}()
 	close(c.quit)
-	<-c.shutdown
 	return
 }
 
+func (c *client) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+
+	select {
+	case c.flushes <- done:
+	case <-c.quit:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // Asychronously send a batched requests.
 func (c *client) sendAsync(msgs []message, wg *sync.WaitGroup, ex *executor) {
 	wg.Add(1)
+	c.counters.add(&c.counters.inFlight, 1)
 
 	if !ex.do(func() {
 		defer wg.Done()
+		defer c.counters.add(&c.counters.inFlight, -1)
 		defer func() {
 			// In case a bug is introduced in the send function that triggers
 			// a panic, we don't want this to ever crash the application so we
 			// catch it here and log it instead.
 			if err := recover(); err != nil {
-				c.errorf("panic - %s", err)
+				c.error("panic while sending messages", Field{"messageCount", len(msgs)}, Field{"error", err})
 			}
 		}()
 		c.send(msgs)
 	}) {
 		wg.Done()
-		c.errorf("sending messages failed - %s", ErrTooManyRequests)
+		c.counters.add(&c.counters.inFlight, -1)
+		c.counters.add(&c.counters.rejected, 1)
+		c.error("sending messages failed", Field{"messageCount", len(msgs)}, Field{"error", ErrTooManyRequests})
 		c.notifyFailure(msgs, ErrTooManyRequests)
+		c.ack(msgs)
 	}
 }
 
 // Send batch request.
 func (c *client) send(msgs []message) {
-	const attempts = 10
+	info := BatchInfo{
+		MessageId: c.uid(),
+		Messages:  len(msgs),
+	}
 
 	b, err := json.Marshal(batch{
-		MessageId: c.uid(),
+		MessageId: info.MessageId,
 		SentAt:    c.now(),
 		Messages:  msgs,
 		Context:   c.DefaultContext,
 	})
 
 	if err != nil {
-		c.errorf("marshalling messages - %s", err)
+		c.error("marshalling messages failed", Field{"batchId", info.MessageId}, Field{"messageCount", len(msgs)}, Field{"error", err})
 		c.notifyFailure(msgs, err)
+		c.ack(msgs)
 		return
 	}
 
-	for i := 0; i != attempts; i++ {
-		if err = c.upload(b); err == nil {
+	info.Bytes = len(b)
+	c.counters.batchMessages.observe(float64(len(msgs)))
+	c.counters.batchBytes.observe(float64(len(b)))
+
+	// The batch is compressed once so retries don't have to do it again. Limits
+	// on the batch size were enforced on the uncompressed representation, which
+	// is also the one written to the dead-letter sink.
+	raw := b
+
+	if b, err = c.Compression.compress(b); err != nil {
+		c.error("compressing messages failed", Field{"batchId", info.MessageId}, Field{"messageCount", len(msgs)}, Field{"error", err})
+		c.notifyFailure(msgs, err)
+		c.ack(msgs)
+		return
+	}
+
+	ctx, end := c.Tracer.StartSend(c.ctx, info)
+	defer func() { end(err) }()
+
+	for i := 0; i != c.MaxAttempts; i++ {
+		// Uploads of all batches are paused while the API asked the client to
+		// slow down.
+		if !c.sleep(c.pause.remaining(time.Now())) {
+			err = c.abandon(msgs, err)
+			c.deadLetter(msgs, raw, i, err)
+			return
+		}
+
+		if i != 0 {
+			c.counters.add(&c.counters.retries, 1)
+		}
+
+		if err = c.upload(ctx, b, info, i); err == nil {
 			c.notifySuccess(msgs)
+			c.ack(msgs)
 			return
 		}
 
-		// Wait for either a retry timeout or the client to be closed.
-		select {
-		case <-time.After(c.RetryAfter(i)):
-		case <-c.quit:
-			c.errorf("%d messages dropped because they failed to be sent and the client was closed", len(msgs))
+		if !c.RetryPolicy(err) {
+			// The messages will never be accepted by the API, there is no point
+			// in keeping them in the storage either.
+			c.error("messages dropped because they failed to be sent with a permanent error", Field{"batchId", info.MessageId}, Field{"attempt", i}, Field{"messageCount", len(msgs)}, Field{"error", err})
 			c.notifyFailure(msgs, err)
+			c.ack(msgs)
 			return
 		}
+
+		wait := c.RetryAfter(i)
+
+		if d, ok := retryAfter(err, time.Now()); ok {
+			c.info("pausing uploads as requested by the API", Field{"batchId", info.MessageId}, Field{"attempt", i}, Field{"delay", d})
+			c.pause.extend(time.Now().Add(d))
+
+			if d > wait {
+				wait = d
+			}
+		}
+
+		// Wait for either a retry timeout or the client to be closed.
+		if !c.sleep(wait) {
+			err = c.abandon(msgs, err)
+			c.deadLetter(msgs, raw, i+1, err)
+			return
+		}
+	}
+
+	c.error("messages dropped because they failed to be sent after all attempts", Field{"batchId", info.MessageId}, Field{"attempt", c.MaxAttempts - 1}, Field{"messageCount", len(msgs)}, Field{"error", err})
+	c.notifyFailure(msgs, err)
+
+	if c.DeadLetter != nil {
+		c.deadLetter(msgs, raw, c.MaxAttempts, err)
+	} else {
+		c.ack(msgs)
+	}
+}
+
+// Waits for the given duration, returns false if the client stopped retrying
+// failed uploads in the meantime.
+func (c *client) sleep(d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-c.abort:
+		return false
 	}
+}
 
-	c.errorf("%d messages dropped because they failed to be sent after %d attempts", len(msgs), attempts)
+// Reports messages that were not sent because the client was closed, err is
+// the error of the last failed attempt (if any). The method returns the error
+// that the messages were reported with.
+func (c *client) abandon(msgs []message, err error) error {
+	if err == nil || c.ctx.Err() != nil {
+		err = ErrAbandoned
+	}
+	c.error("messages dropped because they failed to be sent and the client was closed", Field{"messageCount", len(msgs)}, Field{"error", err})
 	c.notifyFailure(msgs, err)
+	return err
 }
 
-// Upload serialized batch message.
-func (c *client) upload(b []byte) error {
+// Upload serialized batch message, attempt is the index of the upload among the
+// attempts at sending the batch.
+func (c *client) upload(ctx context.Context, b []byte, info BatchInfo, attempt int) (err error) {
 	url := c.Endpoint + "/v1/batch"
 	req, err := http.NewRequest("POST", url, bytes.NewReader(b))
 	if err != nil {
-		c.errorf("creating request - %s", err)
+		c.error("creating request failed", Field{"batchId", info.MessageId}, Field{"attempt", attempt}, Field{"error", err})
 		return err
 	}
 
+	status := 0
+	req, end := c.Tracer.StartUpload(req.WithContext(ctx), info, attempt)
+	defer func() { end(status, err) }()
+
 	req.Header.Add("User-Agent", "analytics-go (version: "+Version+")")
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Content-Length", strconv.Itoa(len(b)))
+	if enc := c.Compression.contentEncoding(); len(enc) != 0 {
+		req.Header.Add("Content-Encoding", enc)
+	}
 	req.SetBasicAuth(c.key, "")
 
+	t0 := time.Now()
 	res, err := c.http.Do(req)
+	c.counters.uploadLatency.observe(time.Since(t0).Seconds())
 
 	if err != nil {
-		c.errorf("sending request - %s", err)
+		c.error("sending request failed", Field{"batchId", info.MessageId}, Field{"attempt", attempt}, Field{"error", err})
 		return err
 	}
 
 	defer res.Body.Close()
-	return c.report(res)
+	status = res.StatusCode
+	return c.report(res, info, attempt)
 }
 
 // Report on response body.
-func (c *client) report(res *http.Response) (err error) {
+func (c *client) report(res *http.Response, info BatchInfo, attempt int) (err error) {
 	var body []byte
 
+	c.counters.addStatusCode(res.StatusCode)
+
 	if res.StatusCode < 300 {
-		c.debugf("response %s", res.Status)
+		c.debug("response received", Field{"batchId", info.MessageId}, Field{"attempt", attempt}, Field{"status", res.StatusCode})
 		return
 	}
 
 	if body, err = ioutil.ReadAll(res.Body); err != nil {
-		c.errorf("response %d %s - %s", res.StatusCode, res.Status, err)
+		c.error("reading response failed", Field{"batchId", info.MessageId}, Field{"attempt", attempt}, Field{"status", res.StatusCode}, Field{"error", err})
 		return
 	}
 
-	c.logf("response %d %s – %s", res.StatusCode, res.Status, string(body))
-	return fmt.Errorf("%d %s", res.StatusCode, res.Status)
+	c.info("response received", Field{"batchId", info.MessageId}, Field{"attempt", attempt}, Field{"status", res.StatusCode}, Field{"body", string(body)})
+	return HTTPError{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       body,
+	}
+}
+
+// Loads the messages that were left unacknowledged in the client storage, so
+// they can be pushed to the batch loop before any new message.
+func (c *client) replay() (msgs []message, err error) {
+	var stored []StoredMessage
+
+	if stored, err = c.Storage.Replay(); err != nil {
+		return
+	}
+
+	for _, s := range stored {
+		m, err := decodeMessage(s.Data)
+
+		if err != nil {
+			// Messages that can't be decoded will never be sent successfully,
+			// so they are dropped from the storage instead of being replayed
+			// every time a client is created.
+			c.error("decoding stored message failed", Field{"seq", s.Seq}, Field{"error", err})
+			c.ack([]message{{seq: s.Seq}})
+			continue
+		}
+
+		msgs = append(msgs, message{
+			msg:  m,
+			json: s.Data,
+			seq:  s.Seq,
+		})
+	}
+
+	if len(msgs) != 0 {
+		c.info("replaying messages from storage", Field{"messageCount", len(msgs)})
+	}
+
+	return
 }
 
 // Batch loop.
-func (c *client) loop() {
+func (c *client) loop(replay []message) {
 	defer close(c.shutdown)
+	defer c.closeStorage()
+	defer c.closeDeadLetter()
+	defer c.cancel()
 
 	wg := &sync.WaitGroup{}
 	defer wg.Wait()
 
+	// Batches are tracked in groups that are retired on each flush request, so
+	// flushes only wait for the batches that were sent before they were made.
+	// Groups complete in the order they were retired, the retired channel is
+	// closed once all batches of the groups retired so far were sent.
+	batches := c.newBatchGroup(wg)
+	retired := make(chan struct{})
+	close(retired)
+
 	tick := time.NewTicker(c.Interval)
 	defer tick.Stop()
 
@@ -341,68 +755,125 @@ func (c *client) loop() {
 		maxBatchBytes: c.maxBatchBytes(),
 	}
 
+	for _, msg := range replay {
+		c.push(&mq, msg, batches, ex)
+	}
+
 	for {
 		select {
 		case msg := <-c.msgs:
-			c.push(&mq, msg, wg, ex)
+			c.push(&mq, msg, batches, ex)
 
 		case <-tick.C:
-			c.flush(&mq, wg, ex)
+			c.flush(&mq, batches, ex)
+
+		case done := <-c.flushes:
+			// Messages that were queued before the flush was requested may
+			// still be sitting in the channel buffer.
+			for n := len(c.msgs); n != 0; n-- {
+				c.push(&mq, <-c.msgs, batches, ex)
+			}
+
+			c.flush(&mq, batches, ex)
+			go c.retireBatchGroup(wg, batches, retired, done)
+			batches, retired = c.newBatchGroup(wg), done
 
 		case <-c.quit:
-			c.debugf("exit requested – draining messages")
+			c.debug("exit requested, draining messages")
 
 			// Drain the msg channel, we have to close it first so no more
 			// messages can be pushed and otherwise the loop would never end.
 			close(c.msgs)
 			for msg := range c.msgs {
-				c.push(&mq, msg, wg, ex)
+				c.push(&mq, msg, batches, ex)
 			}
 
-			c.flush(&mq, wg, ex)
-			c.debugf("exit")
+			c.flush(&mq, batches, ex)
+			c.retireBatchGroup(wg, batches, retired, make(chan struct{}))
+			c.debug("exit")
 			return
 		}
 	}
 }
 
-func (c *client) push(q *messageQueue, m Message, wg *sync.WaitGroup, ex *executor) {
-	var msg message
-	var err error
+// Creates a wait group tracking a set of batches, the group itself counts as one
+// task in the wait group passed as argument until it is retired.
+func (c *client) newBatchGroup(wg *sync.WaitGroup) *sync.WaitGroup {
+	wg.Add(1)
+	return &sync.WaitGroup{}
+}
 
-	if msg, err = makeMessage(m, maxMessageBytes); err != nil {
-		c.errorf("%s - %v", err, m)
-		c.notifyFailure([]message{{m, nil}}, err)
-		return
+// Waits for all batches of a group and for the groups retired before it to be
+// sent, which is signaled by closing prev, then closes the done channel and
+// releases the group from the wait group passed as first argument.
+// No batches must be added to the group after it was retired.
+func (c *client) retireBatchGroup(wg *sync.WaitGroup, batches *sync.WaitGroup, prev <-chan struct{}, done chan struct{}) {
+	batches.Wait()
+	<-prev
+	close(done)
+	wg.Done()
+}
+
+func (c *client) push(q *messageQueue, msg message, wg *sync.WaitGroup, ex *executor) {
+	// Messages written to the storage were already serialized by `Enqueue`.
+	if msg.json == nil {
+		m := msg.msg
+		var err error
+
+		if msg, err = makeMessage(m, maxMessageBytes); err != nil {
+			c.error("serializing message failed", Field{"message", m}, Field{"error", err})
+			c.notifyFailure([]message{{msg: m}}, err)
+			return
+		}
 	}
 
-	c.debugf("buffer (%d/%d) %v", len(q.pending), c.BatchSize, m)
+	c.counters.messageBytes.observe(float64(len(msg.json)))
+	c.debug("buffering message", Field{"messageCount", len(q.pending) + 1}, Field{"batchSize", c.BatchSize})
 
 	if msgs := q.push(msg); msgs != nil {
-		c.debugf("exceeded messages batch limit with batch of %d messages – flushing", len(msgs))
+		c.debug("exceeded messages batch limit, flushing", Field{"messageCount", len(msgs)})
 		c.sendAsync(msgs, wg, ex)
 	}
+
+	c.counters.set(&c.counters.pending, len(q.pending))
 }
 
 func (c *client) flush(q *messageQueue, wg *sync.WaitGroup, ex *executor) {
 	if msgs := q.flush(); msgs != nil {
-		c.debugf("flushing %d messages", len(msgs))
+		c.debug("flushing messages", Field{"messageCount", len(msgs)})
 		c.sendAsync(msgs, wg, ex)
 	}
+
+	c.counters.set(&c.counters.pending, 0)
 }
 
-func (c *client) debugf(format string, args ...interface{}) {
-	if c.Verbose {
-		c.logf(format, args...)
-	}
+func (c *client) debug(msg string, fields ...Field) {
+	c.log(LevelDebug, msg, fields)
+}
+
+func (c *client) info(msg string, fields ...Field) {
+	c.log(LevelInfo, msg, fields)
 }
 
-func (c *client) logf(format string, args ...interface{}) {
-	c.Logger.Logf(format, args...)
+func (c *client) error(msg string, fields ...Field) {
+	c.log(LevelError, msg, fields)
 }
 
-func (c *client) errorf(format string, args ...interface{}) {
-	c.Logger.Errorf(format, args...)
+func (c *client) log(level LogLevel, msg string, fields []Field) {
+	if level < c.LogLevel {
+		return
+	}
+
+	if l, ok := c.Logger.(StructuredLogger); ok {
+		l.Log(level, msg, fields...)
+		return
+	}
+
+	if level >= LevelError {
+		c.Logger.Errorf("%s", formatLog(msg, fields))
+	} else {
+		c.Logger.Logf("%s", formatLog(msg, fields))
+	}
 }
 
 func (c *client) maxBatchBytes() int {
@@ -414,7 +885,70 @@ func (c *client) maxBatchBytes() int {
 	return maxBatchBytes - len(b)
 }
 
+// Acknowledges the messages in the client storage, which prevents them from
+// being replayed by future clients.
+func (c *client) ack(msgs []message) {
+	if c.Storage == nil {
+		return
+	}
+
+	seqs := make([]uint64, 0, len(msgs))
+
+	for _, m := range msgs {
+		if m.seq != 0 {
+			seqs = append(seqs, m.seq)
+		}
+	}
+
+	if len(seqs) != 0 {
+		if err := c.Storage.Ack(seqs...); err != nil {
+			c.error("acknowledging messages in storage failed", Field{"messageCount", len(seqs)}, Field{"error", err})
+		}
+	}
+}
+
+func (c *client) closeStorage() {
+	if c.Storage != nil {
+		if err := c.Storage.Close(); err != nil {
+			c.error("closing storage failed", Field{"error", err})
+		}
+	}
+}
+
+// Writes a batch that the client gave up on to the dead-letter sink, raw is the
+// uncompressed JSON representation of the batch. Once written the batch is owned
+// by the sink, so its messages are acknowledged in the storage and will not be
+// replayed when a new client is created. Batches that could not be written are
+// left in the storage.
+func (c *client) deadLetter(msgs []message, raw []byte, attempts int, err error) {
+	if c.DeadLetter == nil {
+		return
+	}
+
+	if e := c.DeadLetter.Write(DeadLetterBatch{
+		Time:     c.now(),
+		Error:    err.Error(),
+		Attempts: attempts,
+		Batch:    raw,
+	}); e != nil {
+		c.error("writing batch to dead-letter sink failed", Field{"messageCount", len(msgs)}, Field{"error", e})
+		return
+	}
+
+	c.ack(msgs)
+}
+
+func (c *client) closeDeadLetter() {
+	if c.DeadLetter != nil {
+		if err := c.DeadLetter.Close(); err != nil {
+			c.error("closing dead-letter sink failed", Field{"error", err})
+		}
+	}
+}
+
 func (c *client) notifySuccess(msgs []message) {
+	c.counters.add(&c.counters.delivered, len(msgs))
+
 	if c.Callback != nil {
 		for _, m := range msgs {
 			c.Callback.Success(m.msg)
@@ -423,6 +957,17 @@ func (c *client) notifySuccess(msgs []message) {
 }
 
 func (c *client) notifyFailure(msgs []message, err error) {
+	_, dropped := err.(DroppedError)
+
+	switch {
+	case dropped || err == ErrQueueFull:
+		c.counters.add(&c.counters.dropped, len(msgs))
+	case err == ErrAbandoned:
+		c.counters.add(&c.counters.abandoned, len(msgs))
+	default:
+		c.counters.add(&c.counters.failed, len(msgs))
+	}
+
 	if c.Callback != nil {
 		for _, m := range msgs {
 			c.Callback.Failure(m.msg, err)
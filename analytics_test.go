@@ -2,6 +2,8 @@ package analytics
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,7 +14,9 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -153,8 +157,18 @@ func mockServer() (chan []byte, *httptest.Server) {
 	done := make(chan []byte, 1)
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			z, err := gzip.NewReader(r.Body)
+			if err != nil {
+				panic(err)
+			}
+			body = z
+		}
+
 		buf := bytes.NewBuffer(nil)
-		io.Copy(buf, r.Body)
+		io.Copy(buf, body)
 
 		var v interface{}
 		err := json.Unmarshal(buf.Bytes(), &v)
@@ -347,6 +361,41 @@ func TestEnqueuingCustomTypeFails(t *testing.T) {
 	}
 }
 
+func TestPrepare(t *testing.T) {
+	msg, err := Prepare(&Track{UserId: "1", Event: "Download"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	track, ok := msg.(Track)
+	if !ok {
+		t.Fatalf("invalid message type returned: %T", msg)
+	}
+
+	if track.Type != "track" || len(track.MessageId) == 0 || track.Timestamp.IsZero() {
+		t.Errorf("the message defaults were not set: %#v", track)
+	}
+
+	ts := mockTime()
+
+	if msg, _ := Prepare(Identify{UserId: "1", MessageId: "A", Timestamp: ts}); !reflect.DeepEqual(msg, Identify{
+		Type:      "identify",
+		UserId:    "1",
+		MessageId: "A",
+		Timestamp: ts,
+	}) {
+		t.Errorf("the message id and timestamp should not have been overwritten: %#v", msg)
+	}
+
+	if _, err := Prepare(Track{Event: "Download"}); err == nil {
+		t.Error("no error returned when preparing an invalid message")
+	}
+
+	if _, err := Prepare(&customMessage{}); err == nil {
+		t.Error("no error returned when preparing a message with a custom type")
+	}
+}
+
 func TestTrackWithInterval(t *testing.T) {
 	const interval = 100 * time.Millisecond
 	var ref = fixture("test-interval-track.json")
@@ -782,6 +831,71 @@ func TestClientResponse400(t *testing.T) {
 
 	if err := <-errchan; err == nil {
 		t.Error("failure callback not triggered for a 400 response")
+
+	} else if e, ok := err.(HTTPError); !ok || e.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid error returned for a 400 response: %T: %s", err, err)
+	}
+}
+
+func TestClientRetryPolicy(t *testing.T) {
+	var requests int32
+	errchan := make(chan error, 1)
+
+	client, _ := NewWithConfig("0123456789", Config{
+		Logger: testLogger{t.Logf, t.Logf},
+		Callback: testCallback{
+			nil,
+			func(m Message, e error) { errchan <- e },
+		},
+		Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&requests, 1)
+			return testTransportBadRequest.RoundTrip(r)
+		}),
+		BatchSize:   1,
+		MaxAttempts: 3,
+		RetryAfter:  func(i int) time.Duration { return time.Millisecond },
+		RetryPolicy: func(err error) bool { return true },
+	})
+	defer client.Close()
+
+	client.Enqueue(Track{UserId: "A", Event: "B"})
+
+	if err := <-errchan; err == nil {
+		t.Error("failure callback not triggered after exhausting all attempts")
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Error("invalid number of requests sent:", n)
+	}
+}
+
+func TestClientNoRetryOnPermanentError(t *testing.T) {
+	var requests int32
+	errchan := make(chan error, 1)
+
+	client, _ := NewWithConfig("0123456789", Config{
+		Logger: testLogger{t.Logf, t.Logf},
+		Callback: testCallback{
+			nil,
+			func(m Message, e error) { errchan <- e },
+		},
+		Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&requests, 1)
+			return testTransportBadRequest.RoundTrip(r)
+		}),
+		BatchSize:  1,
+		RetryAfter: func(i int) time.Duration { return time.Hour },
+	})
+	defer client.Close()
+
+	client.Enqueue(Track{UserId: "A", Event: "B"})
+
+	if err := <-errchan; err == nil {
+		t.Error("failure callback not triggered for a 400 response")
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Error("requests failing with a 400 should not be retried:", n)
 	}
 }
 
@@ -840,4 +954,561 @@ func TestClientMaxConcurrentRequests(t *testing.T) {
 	} else if err != ErrTooManyRequests {
 		t.Errorf("invalid error returned by erroring response body: %T: %s", err, err)
 	}
+
+	if n := client.Stats().Rejected; n != 1 {
+		t.Error("invalid number of rejected batches:", n)
+	}
+}
+
+func TestClientStorageReplay(t *testing.T) {
+	var ref = fixture("test-enqueue-track.json")
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	storage, err := NewDiskStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first client never manages to send the message, it should be left in
+	// the storage when the client is closed.
+	client, _ := NewWithConfig("h97jamjwbh", Config{
+		Logger:     testLogger{t.Logf, t.Logf},
+		Transport:  testTransportError,
+		BatchSize:  1,
+		RetryAfter: func(i int) time.Duration { return time.Hour },
+		Storage:    storage,
+		now:        mockTime,
+		uid:        mockId,
+	})
+
+	client.Enqueue(Track{
+		Event:  "Download",
+		UserId: "123456",
+		Properties: Properties{
+			"application": "Segment Desktop",
+			"version":     "1.1.0",
+			"platform":    "osx",
+		},
+	})
+	client.Close()
+
+	if storage, err = NewDiskStorage(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	body, server := mockServer()
+	defer server.Close()
+
+	reschan := make(chan bool, 1)
+
+	client, err = NewWithConfig("h97jamjwbh", Config{
+		Endpoint: server.URL,
+		Logger:   t,
+		Callback: testCallback{
+			func(m Message) { reschan <- true },
+			nil,
+		},
+		Storage: storage,
+		now:     mockTime,
+		uid:     mockId,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The replayed message is sent when the client is closed.
+	client.Close()
+
+	if res := string(<-body); res != ref {
+		t.Errorf("invalid response:\n- expected %s\n- received: %s", ref, res)
+	}
+
+	<-reschan
+
+	// Once delivered the message must have been acknowledged.
+	if storage, err = NewDiskStorage(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	if msgs, _ := storage.Replay(); len(msgs) != 0 {
+		t.Error("delivered messages should not be replayed:", msgs)
+	}
+}
+
+// This type is a storage which fails to replay messages and records whether it
+// was closed.
+type replayErrorStorage struct {
+	Storage
+	closed bool
+}
+
+func (s *replayErrorStorage) Replay() ([]StoredMessage, error) {
+	return nil, testError
+}
+
+func (s *replayErrorStorage) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestClientStorageReplayError(t *testing.T) {
+	storage := &replayErrorStorage{}
+
+	client, err := NewWithConfig("0123456789", Config{
+		Logger:  testLogger{t.Logf, t.Logf},
+		Storage: storage,
+	})
+
+	if err != testError {
+		t.Error("invalid error returned when replaying the storage failed:", err)
+	}
+
+	if client != nil {
+		t.Error("a client was returned along with an error")
+	}
+
+	if !storage.closed {
+		t.Error("the storage was not closed when creating the client failed")
+	}
+}
+
+func TestClientStorageAckFailures(t *testing.T) {
+	tests := map[string]Config{
+		"marshal error": {
+			Transport: testTransportOK,
+			DefaultContext: &Context{
+				Extra: map[string]interface{}{"invalid": func() {}},
+			},
+		},
+		"all attempts failed": {
+			Transport:   testTransportError,
+			MaxAttempts: 1,
+			RetryAfter:  func(i int) time.Duration { return 0 },
+		},
+		"permanent error": {
+			Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				return &http.Response{
+					Status:     http.StatusText(http.StatusBadRequest),
+					StatusCode: http.StatusBadRequest,
+					Body:       ioutil.NopCloser(strings.NewReader("")),
+				}, nil
+			}),
+		},
+	}
+
+	for name, config := range tests {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+
+		storage, err := NewDiskStorage(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		errchan := make(chan error, 1)
+
+		config.Logger = testLogger{t.Logf, t.Logf}
+		config.Callback = testCallback{nil, func(m Message, e error) { errchan <- e }}
+		config.BatchSize = 1
+		config.Storage = storage
+
+		client, _ := NewWithConfig("0123456789", config)
+		client.Enqueue(Track{UserId: "A", Event: "B"})
+		client.Close()
+
+		if err := <-errchan; err == nil {
+			t.Errorf("%s: failure callback not triggered", name)
+		}
+
+		// Messages reported with a final error must not be replayed by the next
+		// client.
+		if storage, err = NewDiskStorage(dir); err != nil {
+			t.Fatal(err)
+		}
+
+		if msgs, _ := storage.Replay(); len(msgs) != 0 {
+			t.Errorf("%s: failed messages should not be replayed: %v", name, msgs)
+		}
+
+		storage.Close()
+	}
+}
+
+func TestClientStorageClosed(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	storage, err := NewDiskStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, _ := NewWithConfig("0123456789", Config{
+		Logger:    testLogger{t.Logf, t.Logf},
+		Transport: testTransportOK,
+		Storage:   storage,
+	})
+	defer client.Close()
+
+	// Simulates the client being closed between the moment Enqueue checked that
+	// it was still open and the moment it wrote the message to the storage.
+	storage.Close()
+
+	if err := client.Enqueue(Track{UserId: "A", Event: "B"}); err != ErrClosed {
+		t.Error("invalid error returned when the storage was closed:", err)
+	}
+}
+
+func TestClientFlush(t *testing.T) {
+	var ref = fixture("test-enqueue-track.json")
+
+	body, server := mockServer()
+	defer server.Close()
+
+	reschan := make(chan bool, 1)
+
+	client, _ := NewWithConfig("h97jamjwbh", Config{
+		Endpoint: server.URL,
+		Logger:   t,
+		Callback: testCallback{
+			func(m Message) { reschan <- true },
+			nil,
+		},
+		// Messages would never be sent without an explicit flush.
+		Interval: time.Hour,
+		now:      mockTime,
+		uid:      mockId,
+	})
+	defer client.Close()
+
+	client.Enqueue(Track{
+		Event:  "Download",
+		UserId: "123456",
+		Properties: Properties{
+			"application": "Segment Desktop",
+			"version":     "1.1.0",
+			"platform":    "osx",
+		},
+	})
+
+	if err := client.Flush(context.Background()); err != nil {
+		t.Error("flushing the client failed:", err)
+	}
+
+	// The batch must have been sent by the time Flush returned.
+	select {
+	case <-reschan:
+	default:
+		t.Error("the success callback was not called before Flush returned")
+	}
+
+	if res := string(<-body); res != ref {
+		t.Errorf("invalid response:\n- expected %s\n- received: %s", ref, res)
+	}
+}
+
+func TestClientFlushContext(t *testing.T) {
+	release := make(chan struct{})
+
+	client, _ := NewWithConfig("0123456789", Config{
+		Logger: testLogger{t.Logf, t.Logf},
+		Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			<-release
+			return testTransportOK.RoundTrip(r)
+		}),
+		Interval: time.Hour,
+	})
+	defer client.Close()
+	defer close(release)
+
+	client.Enqueue(Track{UserId: "A", Event: "B"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := client.Flush(ctx); err != context.DeadlineExceeded {
+		t.Error("invalid error returned when the flush timed out:", err)
+	}
+}
+
+func TestClientFlushAfterTimeout(t *testing.T) {
+	var failing int32 = 1
+	var delivered int32
+
+	client, _ := NewWithConfig("0123456789", Config{
+		Logger: testLogger{t.Logf, t.Logf},
+		Callback: testCallback{
+			func(m Message) { atomic.AddInt32(&delivered, 1) },
+			nil,
+		},
+		Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if atomic.LoadInt32(&failing) != 0 {
+				return testTransportError.RoundTrip(r)
+			}
+			return testTransportOK.RoundTrip(r)
+		}),
+		RetryAfter:  func(int) time.Duration { return time.Millisecond },
+		MaxAttempts: 1000000,
+		Interval:    time.Hour,
+	})
+	defer client.Close()
+
+	client.Enqueue(Track{UserId: "A", Event: "B"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := client.Flush(ctx); err != context.DeadlineExceeded {
+		t.Fatal("invalid error returned when the flush timed out:", err)
+	}
+
+	atomic.StoreInt32(&failing, 0)
+
+	// The batch that was still being retried when the first flush gave up must
+	// be waited for by the next flush, even if no messages were queued since.
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatal("flushing the client failed:", err)
+	}
+
+	if n := atomic.LoadInt32(&delivered); n != 1 {
+		t.Error("the batch was not delivered by the time Flush returned:", n)
+	}
+}
+
+func TestClientFlushConcurrent(t *testing.T) {
+	release := make(chan struct{})
+	var delivered int32
+
+	client, _ := NewWithConfig("0123456789", Config{
+		Logger: testLogger{t.Logf, t.Logf},
+		Callback: testCallback{
+			func(m Message) { atomic.AddInt32(&delivered, 1) },
+			nil,
+		},
+		Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			<-release
+			return testTransportOK.RoundTrip(r)
+		}),
+		Interval: time.Hour,
+	})
+	defer client.Close()
+
+	client.Enqueue(Track{UserId: "A", Event: "B"})
+
+	errs := make(chan error, 2)
+	go func() { errs <- client.Flush(context.Background()) }()
+	go func() { errs <- client.Flush(context.Background()) }()
+
+	returned := 0
+
+	select {
+	case err := <-errs:
+		t.Error("Flush returned before the batch was sent:", err)
+		returned++
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+
+	for i := returned; i != 2; i++ {
+		if err := <-errs; err != nil {
+			t.Error("flushing the client failed:", err)
+		}
+	}
+
+	if n := atomic.LoadInt32(&delivered); n != 1 {
+		t.Error("invalid number of messages delivered:", n)
+	}
+}
+
+func TestClientFlushClosed(t *testing.T) {
+	client := New("0123456789")
+	client.Close()
+
+	if err := client.Flush(context.Background()); err != ErrClosed {
+		t.Error("flushing a client after it was closed should return ErrClosed:", err)
+	}
+}
+
+func TestClientCloseContext(t *testing.T) {
+	client, _ := NewWithConfig("0123456789", Config{
+		Logger:    testLogger{t.Logf, t.Logf},
+		Transport: testTransportOK,
+	})
+
+	client.Enqueue(Track{UserId: "A", Event: "B"})
+
+	if err := client.CloseContext(context.Background()); err != nil {
+		t.Error("closing a client that delivered all its messages should not return an error:", err)
+	}
+
+	if err := client.CloseContext(context.Background()); err != ErrClosed {
+		t.Error("closing a client a second time should return ErrClosed:", err)
+	}
+}
+
+func TestClientCloseContextDeadline(t *testing.T) {
+	errchan := make(chan error, 1)
+
+	client, _ := NewWithConfig("0123456789", Config{
+		Logger: testLogger{t.Logf, t.Logf},
+		Callback: testCallback{
+			nil,
+			func(m Message, e error) { errchan <- e },
+		},
+		Transport:  testTransportError,
+		BatchSize:  1,
+		RetryAfter: func(i int) time.Duration { return time.Hour },
+	})
+
+	client.Enqueue(Track{UserId: "A", Event: "B"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := client.CloseContext(ctx); err != (CloseError{Abandoned: 1}) {
+		t.Errorf("invalid error returned when the close deadline was exceeded: %#v", err)
+	}
+
+	if err := <-errchan; err != ErrAbandoned {
+		t.Error("invalid error reported to the callback for an abandoned message:", err)
+	}
+}
+
+func TestTrackWithCompression(t *testing.T) {
+	var ref = fixture("test-enqueue-track.json")
+
+	body, server := mockServer()
+	defer server.Close()
+
+	client, _ := NewWithConfig("h97jamjwbh", Config{
+		Endpoint:    server.URL,
+		Verbose:     true,
+		Logger:      t,
+		BatchSize:   1,
+		Compression: CompressionGzip,
+		now:         mockTime,
+		uid:         mockId,
+	})
+	defer client.Close()
+
+	client.Enqueue(Track{
+		Event:  "Download",
+		UserId: "123456",
+		Properties: Properties{
+			"application": "Segment Desktop",
+			"version":     "1.1.0",
+			"platform":    "osx",
+		},
+	})
+
+	if res := string(<-body); ref != res {
+		t.Errorf("invalid response:\n- expected %s\n- received: %s", ref, res)
+	}
+}
+
+func TestClientRetryAfterHeader(t *testing.T) {
+	var requests int32
+	reschan := make(chan time.Time, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewWithConfig("0123456789", Config{
+		Endpoint: server.URL,
+		Logger:   testLogger{t.Logf, t.Logf},
+		Callback: testCallback{
+			func(m Message) { reschan <- time.Now() },
+			nil,
+		},
+		BatchSize:  1,
+		RetryAfter: func(i int) time.Duration { return time.Millisecond },
+	})
+	defer client.Close()
+
+	t0 := time.Now()
+	client.Enqueue(Track{UserId: "A", Event: "B"})
+
+	if t1 := <-reschan; t1.Sub(t0) < time.Second {
+		t.Error("the client did not wait as long as requested by the Retry-After header:", t1.Sub(t0))
+	}
+}
+
+func TestClientStats(t *testing.T) {
+	body, server := mockServer()
+	defer server.Close()
+
+	client, _ := NewWithConfig("h97jamjwbh", Config{
+		Endpoint: server.URL,
+		Logger:   t,
+		Interval: time.Hour,
+	})
+	defer client.Close()
+
+	for i := 0; i != 3; i++ {
+		client.Enqueue(Track{UserId: "A", Event: "B"})
+	}
+
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatal("flushing the client failed:", err)
+	}
+	<-body
+
+	s := client.Stats()
+
+	if s.Enqueued != 3 || s.Delivered != 3 || s.Failed != 0 || s.Retries != 0 {
+		t.Errorf("invalid stats counters: %#v", s)
+	}
+
+	if s.QueueDepth != 0 || s.InFlight != 0 {
+		t.Errorf("invalid stats gauges after flushing: %d queued, %d in flight", s.QueueDepth, s.InFlight)
+	}
+
+	if s.BatchMessages.Count != 1 || s.BatchMessages.Sum != 3 {
+		t.Errorf("invalid batch messages histogram: %#v", s.BatchMessages)
+	}
+
+	if s.BatchBytes.Sum == 0 || s.UploadLatency.Count != 1 {
+		t.Errorf("invalid batch bytes or upload latency histograms: %#v, %#v", s.BatchBytes, s.UploadLatency)
+	}
+
+	if s.MessageBytes.Count != 3 {
+		t.Errorf("invalid message bytes histogram: %#v", s.MessageBytes)
+	}
+
+	if len(s.StatusCodes) != 1 || s.StatusCodes[200] != 1 {
+		t.Error("invalid status codes:", s.StatusCodes)
+	}
+}
+
+func TestClientStatsRetries(t *testing.T) {
+	errchan := make(chan error, 1)
+
+	client, _ := NewWithConfig("0123456789", Config{
+		Logger: testLogger{t.Logf, t.Logf},
+		Callback: testCallback{
+			nil,
+			func(m Message, e error) { errchan <- e },
+		},
+		Transport:   testTransportError,
+		BatchSize:   1,
+		MaxAttempts: 3,
+		RetryAfter:  func(i int) time.Duration { return time.Millisecond },
+	})
+	defer client.Close()
+
+	client.Enqueue(Track{UserId: "A", Event: "B"})
+	<-errchan
+
+	if s := client.Stats(); s.Retries != 2 || s.Failed != 1 || s.UploadLatency.Count != 3 {
+		t.Errorf("invalid stats after exhausting all attempts: %#v", s)
+	}
 }
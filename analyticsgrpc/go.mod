@@ -0,0 +1,25 @@
+module github.com/segmentio/analytics-go/analyticsgrpc
+
+go 1.20
+
+require (
+	github.com/segmentio/analytics-go/v3 v3.0.1-0.20261017021445-11bb36e0a196
+	google.golang.org/grpc v1.64.1
+)
+
+require (
+	github.com/bmizerany/assert v0.0.0-20160611221934-b7ed37b82869 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/kr/pretty v0.3.1 // indirect
+	github.com/segmentio/backo-go v1.0.0 // indirect
+	golang.org/x/net v0.35.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
+	golang.org/x/text v0.22.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237 // indirect
+	google.golang.org/protobuf v1.34.2 // indirect
+)
+
+// The module is developed against the local copy of the analytics package,
+// programs depending on it use the version required above, which must provide
+// every API used by this module.
+replace github.com/segmentio/analytics-go/v3 => ../
@@ -0,0 +1,209 @@
+// Package analyticsgrpc provides gRPC server interceptors which track the RPC
+// calls handled by a server with an analytics client.
+//
+// The package lives in its own module so programs that use the analytics
+// package without gRPC don't inherit its dependencies.
+//
+// Here's a quick example of how this package is meant to be used:
+//
+//	config := analyticsgrpc.Config{
+//		Identity: analyticsgrpc.MetadataIdentity("x-user-id", "x-anonymous-id"),
+//	}
+//
+//	server := grpc.NewServer(
+//		grpc.UnaryInterceptor(analyticsgrpc.UnaryServerInterceptor(client, config)),
+//		grpc.StreamInterceptor(analyticsgrpc.StreamServerInterceptor(client, config)),
+//	)
+package analyticsgrpc
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/segmentio/analytics-go/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// This constant is the name of the events tracked by the interceptors if none
+// was explicitly set.
+const DefaultEvent = "RPC Completed"
+
+// IdentityFunc is the signature of functions used by the interceptors to
+// extract the identity of the user who made a call from the incoming metadata
+// of the call.
+type IdentityFunc func(ctx context.Context, md metadata.MD) (userId string, anonymousId string)
+
+// MetadataIdentity returns an IdentityFunc which reads the user id and the
+// anonymous id from the metadata keys passed as arguments. Either of the keys
+// may be empty if the corresponding id is never set.
+func MetadataIdentity(userIdKey string, anonymousIdKey string) IdentityFunc {
+	return func(ctx context.Context, md metadata.MD) (string, string) {
+		return metadataValue(md, userIdKey), metadataValue(md, anonymousIdKey)
+	}
+}
+
+// Instances of this type carry the different configuration options that may
+// be set when creating interceptors.
+//
+// Each field's zero-value is either meaningful or interpreted as using the
+// default value defined by the package.
+type Config struct {
+
+	// The name of the events tracked for each call, set to `DefaultEvent` by
+	// default.
+	Event string
+
+	// The function used to extract the identity of the user who made a call.
+	// When the function returns no identity the one carried by the context of
+	// the call is used, see analytics.WithIdentity. Messages need a user id or
+	// an anonymous id to be valid, calls without identity are not tracked.
+	// If none is specified the interceptors read the "user-id" and
+	// "anonymous-id" metadata keys.
+	Identity IdentityFunc
+
+	// How long the interceptors may wait for room in the queue of the client
+	// when tracking a call, set to `analytics.DefaultEnqueueTimeout` by
+	// default.
+	// Events are queued with `EnqueueContext` and the context of the call,
+	// bounded by this timeout, so a backed-up client never delays responses
+	// by more than the timeout whatever its overflow policy is. Events that
+	// could not be queued in time are dropped.
+	EnqueueTimeout time.Duration
+}
+
+func makeConfig(config Config) Config {
+	if len(config.Event) == 0 {
+		config.Event = DefaultEvent
+	}
+
+	if config.Identity == nil {
+		config.Identity = MetadataIdentity("user-id", "anonymous-id")
+	}
+
+	if config.EnqueueTimeout == 0 {
+		config.EnqueueTimeout = analytics.DefaultEnqueueTimeout
+	}
+
+	return config
+}
+
+// UnaryServerInterceptor returns a gRPC interceptor which tracks an event with
+// the client for each unary call handled by the server.
+//
+// The events carry the full method name, the service, the status code and the
+// duration of calls as properties, and the peer IP and user agent as context.
+func UnaryServerInterceptor(client analytics.Client, config Config) grpc.UnaryServerInterceptor {
+	config = makeConfig(config)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		res, err := handler(ctx, req)
+		track(client, config, ctx, info.FullMethod, start, err)
+		return res, err
+	}
+}
+
+// StreamServerInterceptor returns a gRPC interceptor which tracks an event with
+// the client for each streaming call handled by the server, when the stream
+// completes.
+//
+// The events carry the same properties and context than the ones tracked by
+// `UnaryServerInterceptor`.
+func StreamServerInterceptor(client analytics.Client, config Config) grpc.StreamServerInterceptor {
+	config = makeConfig(config)
+
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, stream)
+		track(client, config, stream.Context(), info.FullMethod, start, err)
+		return err
+	}
+}
+
+func track(client analytics.Client, config Config, ctx context.Context, method string, start time.Time, err error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	userId, anonymousId := config.Identity(ctx, md)
+
+	if len(userId) == 0 && len(anonymousId) == 0 {
+		userId, anonymousId = analytics.IdentityFromContext(ctx)
+	}
+
+	if len(userId) == 0 && len(anonymousId) == 0 {
+		return
+	}
+
+	service, name := splitMethod(method)
+
+	ctx, cancel := context.WithTimeout(ctx, config.EnqueueTimeout)
+	defer cancel()
+
+	// Errors are ignored, tracking a call must not change its outcome.
+	client.EnqueueContext(ctx, analytics.Track{
+		UserId:      userId,
+		AnonymousId: anonymousId,
+		Event:       config.Event,
+		Properties: analytics.Properties{
+			"method":      method,
+			"service":     service,
+			"rpc":         name,
+			"code":        status.Code(err).String(),
+			"duration_ms": float64(time.Since(start)) / float64(time.Millisecond),
+		},
+		Context: &analytics.Context{
+			IP:        peerIP(ctx),
+			UserAgent: metadataValue(md, "user-agent"),
+		},
+	})
+}
+
+// Splits a full method name of the form "/package.Service/Method" into the
+// service and method names.
+func splitMethod(method string) (string, string) {
+	method = strings.TrimPrefix(method, "/")
+
+	if i := strings.LastIndexByte(method, '/'); i >= 0 {
+		return method[:i], method[i+1:]
+	}
+
+	return "", method
+}
+
+// Returns the IP address of the peer of the call, or nil if the peer address
+// is not an IP address (for example with in-memory connections).
+func peerIP(ctx context.Context) net.IP {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return nil
+	}
+
+	switch addr := p.Addr.(type) {
+	case *net.TCPAddr:
+		return addr.IP
+	case *net.UDPAddr:
+		return addr.IP
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+
+	return net.ParseIP(host)
+}
+
+func metadataValue(md metadata.MD, key string) string {
+	if len(key) == 0 {
+		return ""
+	}
+
+	if values := md.Get(key); len(values) != 0 {
+		return values[0]
+	}
+
+	return ""
+}
@@ -0,0 +1,227 @@
+package analyticsgrpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/segmentio/analytics-go/v3"
+	"github.com/segmentio/analytics-go/v3/analyticstest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// This type is an analytics client whose queue is always full, queuing messages
+// blocks until the context is canceled.
+type blockingClient struct {
+	analytics.Client
+}
+
+func (blockingClient) EnqueueContext(ctx context.Context, msg analytics.Message) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// Starts a gRPC server exposing the health service on an in-memory listener,
+// and returns a client connected to it.
+func startServer(t *testing.T, rec analytics.Client, config Config) healthpb.HealthClient {
+	lis := bufconn.Listen(1024 * 1024)
+
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(rec, config)),
+		grpc.StreamInterceptor(StreamServerInterceptor(rec, config)),
+	)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("test", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUserAgent("test"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	rec := analyticstest.NewRecorder()
+	client := startServer(t, rec, Config{})
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "user-id", "1")
+
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "unknown"}); status.Code(err) != codes.NotFound {
+		t.Fatal("unexpected error:", err)
+	}
+
+	tracks := rec.Tracks()
+	if len(tracks) != 2 {
+		t.Fatalf("expected 2 tracked calls, found %d", len(tracks))
+	}
+
+	for i, code := range []string{"OK", "NotFound"} {
+		track := tracks[i]
+
+		if track.Event != DefaultEvent || track.UserId != "1" {
+			t.Errorf("invalid track message: %#v", track)
+		}
+
+		if track.Properties["method"] != "/grpc.health.v1.Health/Check" ||
+			track.Properties["service"] != "grpc.health.v1.Health" ||
+			track.Properties["rpc"] != "Check" ||
+			track.Properties["code"] != code {
+			t.Errorf("invalid track properties: %#v", track.Properties)
+		}
+
+		if d, ok := track.Properties["duration_ms"].(float64); !ok || d < 0 {
+			t.Errorf("invalid call duration: %#v", track.Properties["duration_ms"])
+		}
+
+		if ua := track.Context.UserAgent; len(ua) < 4 || ua[:4] != "test" {
+			t.Errorf("invalid user agent: %q", ua)
+		}
+	}
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	rec := analyticstest.NewRecorder()
+	client := startServer(t, rec, Config{
+		Event:    "Health Watched",
+		Identity: MetadataIdentity("", "x-anonymous-id"),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-anonymous-id", "A")
+
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+
+	// The call is tracked once the server handler returns, which happens
+	// asynchronously after the client canceled the stream.
+	for i := 0; len(rec.Tracks()) == 0; i++ {
+		if i == 1000 {
+			t.Fatal("the stream call was not tracked")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	track := rec.Tracks()[0]
+
+	if track.Event != "Health Watched" || track.AnonymousId != "A" || track.UserId != "" {
+		t.Errorf("invalid track message: %#v", track)
+	}
+
+	if track.Properties["method"] != "/grpc.health.v1.Health/Watch" || track.Properties["code"] != "Canceled" {
+		t.Errorf("invalid track properties: %#v", track.Properties)
+	}
+}
+
+func TestInterceptorNoIdentity(t *testing.T) {
+	rec := analyticstest.NewRecorder()
+	client := startServer(t, rec, Config{})
+
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if msgs := rec.Messages(); len(msgs) != 0 {
+		t.Error("calls without identity should not be tracked:", msgs)
+	}
+}
+
+func TestInterceptorIdentityFromContext(t *testing.T) {
+	rec := analyticstest.NewRecorder()
+	intercept := UnaryServerInterceptor(rec, Config{})
+
+	ctx := analytics.WithIdentity(context.Background(), "1", "")
+	info := &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}
+
+	intercept(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+
+	if tracks := rec.Tracks(); len(tracks) != 1 || tracks[0].UserId != "1" {
+		t.Errorf("the identity carried by the context was not used: %#v", tracks)
+	}
+}
+
+func TestInterceptorEnqueueTimeout(t *testing.T) {
+	client := startServer(t, blockingClient{}, Config{
+		EnqueueTimeout: 10 * time.Millisecond,
+	})
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "user-id", "1")
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	// A backed-up analytics client must not prevent the call from completing.
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "test"}); err != nil {
+		t.Fatal("the call did not complete while the analytics client was blocked:", err)
+	}
+}
+
+func TestPeerIP(t *testing.T) {
+	tests := []struct {
+		addr net.Addr
+		ip   net.IP
+	}{
+		{&net.TCPAddr{IP: net.ParseIP("1.2.3.4"), Port: 1234}, net.ParseIP("1.2.3.4")},
+		{&net.UnixAddr{Name: "/tmp/grpc.sock", Net: "unix"}, nil},
+		{nil, nil},
+	}
+
+	for _, test := range tests {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: test.addr})
+
+		if ip := peerIP(ctx); !ip.Equal(test.ip) {
+			t.Errorf("%v: expected peer IP %v, found %v", test.addr, test.ip, ip)
+		}
+	}
+}
+
+func TestSplitMethod(t *testing.T) {
+	tests := []struct {
+		method  string
+		service string
+		name    string
+	}{
+		{"/grpc.health.v1.Health/Check", "grpc.health.v1.Health", "Check"},
+		{"Check", "", "Check"},
+	}
+
+	for _, test := range tests {
+		if service, name := splitMethod(test.method); service != test.service || name != test.name {
+			t.Errorf("%s: expected %q and %q, found %q and %q", test.method, test.service, test.name, service, name)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+// Package analyticshttp provides a net/http middleware which builds the
+// analytics context of messages from the requests received by a server.
+//
+// Here's a quick example of how this package is meant to be used:
+//
+//	mux := http.NewServeMux()
+//	mux.HandleFunc("/signup", func(w http.ResponseWriter, r *http.Request) {
+//		client.EnqueueContext(r.Context(), analytics.Track{
+//			UserId: "0123456789",
+//			Event:  "Signed Up",
+//		})
+//	})
+//
+//	http.ListenAndServe(":8080", analyticshttp.Middleware{}.Handler(mux))
+package analyticshttp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/segmentio/analytics-go/v3"
+)
+
+// Middleware builds an analytics context for each request passed to the
+// handlers it wraps, the context is stored in the context of the request and
+// is merged into the messages queued with EnqueueContext.
+//
+// The zero-value is a valid middleware which trusts no proxies, the client IP
+// of requests is then always the remote address of their connection.
+type Middleware struct {
+	// The networks of the proxies trusted to set the X-Forwarded-For and
+	// X-Forwarded-Proto headers of the requests, see ParseTrustedProxies.
+	TrustedProxies []*net.IPNet
+}
+
+// ParseTrustedProxies parses a list of IP addresses or CIDR networks (for
+// example "10.0.0.0/8") into a list of networks that can be used as trusted
+// proxies of a middleware.
+func ParseTrustedProxies(addrs ...string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(addrs))
+
+	for _, addr := range addrs {
+		if strings.Contains(addr, "/") {
+			_, n, err := net.ParseCIDR(addr)
+			if err != nil {
+				return nil, err
+			}
+			nets = append(nets, n)
+			continue
+		}
+
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return nil, &net.ParseError{Type: "IP address", Text: addr}
+		}
+
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+
+		nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+
+	return nets, nil
+}
+
+// Handler returns a http handler which stores the analytics context built from
+// each request in the request context before passing it to h.
+func (m Middleware) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(NewContext(r.Context(), m.Context(r))))
+	})
+}
+
+// Context builds the analytics context of the request passed as argument.
+//
+// The context carries the client IP, user agent, locale, page, referrer and
+// campaign of the request, the campaign is parsed from the utm_* parameters of
+// the request query.
+func (m Middleware) Context(r *http.Request) *analytics.Context {
+	ctx := &analytics.Context{
+		IP:        m.clientIP(r),
+		UserAgent: r.UserAgent(),
+		Locale:    parseLocale(r.Header.Get("Accept-Language")),
+	}
+
+	u := m.requestURL(r)
+	ctx.Page = analytics.PageInfo{
+		Path:     u.Path,
+		URL:      u.String(),
+		Referrer: r.Referer(),
+	}
+
+	if len(u.RawQuery) != 0 {
+		ctx.Page.Search = "?" + u.RawQuery
+	}
+
+	if ref := r.Referer(); len(ref) != 0 {
+		ctx.Referrer = analytics.ReferrerInfo{URL: ref}
+	}
+
+	query := u.Query()
+	ctx.Campaign = analytics.CampaignInfo{
+		Name:    query.Get("utm_campaign"),
+		Source:  query.Get("utm_source"),
+		Medium:  query.Get("utm_medium"),
+		Term:    query.Get("utm_term"),
+		Content: query.Get("utm_content"),
+	}
+
+	return ctx
+}
+
+// Returns the IP address of the client which sent the request.
+//
+// When the request comes from a trusted proxy the addresses of the
+// X-Forwarded-For header are walked from right to left, the client IP is the
+// first address which isn't a trusted proxy.
+func (m Middleware) clientIP(r *http.Request) net.IP {
+	ip := net.ParseIP(remoteHost(r))
+	if ip == nil || !m.trusted(ip) {
+		return ip
+	}
+
+	var hops []string
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			// The header was not set by a proxy we trust, the last valid
+			// address is the best guess we have.
+			break
+		}
+
+		ip = hop
+
+		if !m.trusted(ip) {
+			break
+		}
+	}
+
+	return ip
+}
+
+func (m Middleware) trusted(ip net.IP) bool {
+	for _, n := range m.TrustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the absolute URL of the request, the scheme is taken from the
+// X-Forwarded-Proto header if the request comes from a trusted proxy.
+//
+// Chains of proxies may set the header to a list of schemes, the first one is
+// the scheme used by the client. Values other than http and https are ignored.
+func (m Middleware) requestURL(r *http.Request) *url.URL {
+	u := *r.URL
+	u.Host = r.Host
+	u.Scheme = "http"
+
+	if r.TLS != nil {
+		u.Scheme = "https"
+	}
+
+	if proto := r.Header.Get("X-Forwarded-Proto"); len(proto) != 0 {
+		if ip := net.ParseIP(remoteHost(r)); ip != nil && m.trusted(ip) {
+			if i := strings.IndexByte(proto, ','); i >= 0 {
+				proto = proto[:i]
+			}
+
+			switch proto = strings.ToLower(strings.TrimSpace(proto)); proto {
+			case "http", "https":
+				u.Scheme = proto
+			}
+		}
+	}
+
+	return &u
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Returns the language tag with the highest quality in the value of an
+// Accept-Language header, or an empty string if there are none.
+func parseLocale(header string) string {
+	type language struct {
+		tag string
+		q   float64
+	}
+
+	var langs []language
+
+	for _, part := range strings.Split(header, ",") {
+		args := strings.Split(part, ";")
+		tag := strings.TrimSpace(args[0])
+
+		if len(tag) == 0 || tag == "*" {
+			continue
+		}
+
+		lang := language{tag: tag, q: 1}
+
+		for _, arg := range args[1:] {
+			arg = strings.TrimSpace(arg)
+
+			if strings.HasPrefix(arg, "q=") {
+				if q, err := strconv.ParseFloat(arg[2:], 64); err == nil {
+					lang.q = q
+				}
+			}
+		}
+
+		if lang.q > 0 {
+			langs = append(langs, lang)
+		}
+	}
+
+	if len(langs) == 0 {
+		return ""
+	}
+
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].q > langs[j].q
+	})
+
+	return langs[0].tag
+}
+
+// NewContext returns a copy of ctx which carries the analytics context c, it is
+// equivalent to analytics.WithAnalyticsContext.
+func NewContext(ctx context.Context, c *analytics.Context) context.Context {
+	return analytics.WithAnalyticsContext(ctx, c)
+}
+
+// FromContext returns the analytics context carried by ctx, or nil if there
+// is none.
+func FromContext(ctx context.Context) *analytics.Context {
+	return analytics.AnalyticsContextFromContext(ctx)
+}
+
+// Enrich merges the identity and analytics context carried by ctx into msg,
+// fields already set on the message take precedence. It is equivalent to
+// analytics.Enrich, which clients call when messages are queued with
+// EnqueueContext.
+func Enrich(ctx context.Context, msg analytics.Message) analytics.Message {
+	return analytics.Enrich(ctx, msg)
+}
@@ -0,0 +1,281 @@
+package analyticshttp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/segmentio/analytics-go/v3"
+	"github.com/segmentio/analytics-go/v3/analyticstest"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	nets, err := ParseTrustedProxies("10.0.0.0/8", "192.168.1.1", "::1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ip      string
+		trusted bool
+	}{
+		{"10.1.2.3", true},
+		{"192.168.1.1", true},
+		{"192.168.1.2", false},
+		{"::1", true},
+		{"1.2.3.4", false},
+	}
+
+	m := Middleware{TrustedProxies: nets}
+
+	for _, test := range tests {
+		if trusted := m.trusted(net.ParseIP(test.ip)); trusted != test.trusted {
+			t.Errorf("%s: expected trusted=%t, found %t", test.ip, test.trusted, trusted)
+		}
+	}
+
+	if _, err := ParseTrustedProxies("10.0.0.0/33"); err == nil {
+		t.Error("no error returned for an invalid network")
+	}
+
+	if _, err := ParseTrustedProxies("localhost"); err == nil {
+		t.Error("no error returned for an invalid address")
+	}
+}
+
+func TestMiddlewareClientIP(t *testing.T) {
+	nets, _ := ParseTrustedProxies("10.0.0.0/8")
+	m := Middleware{TrustedProxies: nets}
+
+	tests := []struct {
+		scenario   string
+		remoteAddr string
+		forwarded  []string
+		ip         string
+	}{
+		{
+			scenario:   "no proxy",
+			remoteAddr: "1.2.3.4:5678",
+			ip:         "1.2.3.4",
+		},
+		{
+			scenario:   "untrusted remote address",
+			remoteAddr: "1.2.3.4:5678",
+			forwarded:  []string{"5.6.7.8"},
+			ip:         "1.2.3.4",
+		},
+		{
+			scenario:   "trusted proxy",
+			remoteAddr: "10.0.0.1:5678",
+			forwarded:  []string{"5.6.7.8"},
+			ip:         "5.6.7.8",
+		},
+		{
+			scenario:   "chain of trusted proxies",
+			remoteAddr: "10.0.0.1:5678",
+			forwarded:  []string{"9.9.9.9, 5.6.7.8", "10.0.0.2"},
+			ip:         "5.6.7.8",
+		},
+		{
+			scenario:   "all trusted proxies",
+			remoteAddr: "10.0.0.1:5678",
+			forwarded:  []string{"10.0.0.3, 10.0.0.2"},
+			ip:         "10.0.0.3",
+		},
+		{
+			scenario:   "invalid forwarded address",
+			remoteAddr: "10.0.0.1:5678",
+			forwarded:  []string{"unknown, 10.0.0.2"},
+			ip:         "10.0.0.2",
+		},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = test.remoteAddr
+
+		for _, f := range test.forwarded {
+			r.Header.Add("X-Forwarded-For", f)
+		}
+
+		if ip := m.clientIP(r); !ip.Equal(net.ParseIP(test.ip)) {
+			t.Errorf("%s: expected client IP %s, found %s", test.scenario, test.ip, ip)
+		}
+	}
+}
+
+func TestMiddlewareContext(t *testing.T) {
+	nets, _ := ParseTrustedProxies("10.0.0.1")
+	m := Middleware{TrustedProxies: nets}
+
+	r := httptest.NewRequest("GET", "http://example.com/signup?utm_source=newsletter&utm_medium=email&utm_campaign=launch&utm_term=go&utm_content=banner", nil)
+	r.RemoteAddr = "10.0.0.1:5678"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4")
+	r.Header.Set("X-Forwarded-Proto", "https")
+	r.Header.Set("User-Agent", "test")
+	r.Header.Set("Accept-Language", "fr;q=0.8, en-US, *;q=0.5")
+	r.Header.Set("Referer", "https://google.com/")
+
+	ctx := m.Context(r)
+
+	if !reflect.DeepEqual(ctx, &analytics.Context{
+		IP:        net.ParseIP("1.2.3.4"),
+		UserAgent: "test",
+		Locale:    "en-US",
+		Page: analytics.PageInfo{
+			Path:     "/signup",
+			Referrer: "https://google.com/",
+			Search:   "?utm_source=newsletter&utm_medium=email&utm_campaign=launch&utm_term=go&utm_content=banner",
+			URL:      "https://example.com/signup?utm_source=newsletter&utm_medium=email&utm_campaign=launch&utm_term=go&utm_content=banner",
+		},
+		Referrer: analytics.ReferrerInfo{
+			URL: "https://google.com/",
+		},
+		Campaign: analytics.CampaignInfo{
+			Name:    "launch",
+			Source:  "newsletter",
+			Medium:  "email",
+			Term:    "go",
+			Content: "banner",
+		},
+	}) {
+		t.Errorf("invalid context: %#v", ctx)
+	}
+}
+
+func TestMiddlewareRequestURL(t *testing.T) {
+	nets, _ := ParseTrustedProxies("10.0.0.1")
+	m := Middleware{TrustedProxies: nets}
+
+	tests := []struct {
+		scenario   string
+		remoteAddr string
+		proto      string
+		url        string
+	}{
+		{
+			scenario:   "no header",
+			remoteAddr: "10.0.0.1:5678",
+			url:        "http://example.com/a",
+		},
+		{
+			scenario:   "trusted proxy",
+			remoteAddr: "10.0.0.1:5678",
+			proto:      "HTTPS",
+			url:        "https://example.com/a",
+		},
+		{
+			scenario:   "untrusted remote address",
+			remoteAddr: "1.2.3.4:5678",
+			proto:      "https",
+			url:        "http://example.com/a",
+		},
+		{
+			scenario:   "chain of proxies",
+			remoteAddr: "10.0.0.1:5678",
+			proto:      "https, http",
+			url:        "https://example.com/a",
+		},
+		{
+			scenario:   "invalid scheme",
+			remoteAddr: "10.0.0.1:5678",
+			proto:      "javascript",
+			url:        "http://example.com/a",
+		},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "http://example.com/a", nil)
+		r.RemoteAddr = test.remoteAddr
+
+		if len(test.proto) != 0 {
+			r.Header.Set("X-Forwarded-Proto", test.proto)
+		}
+
+		if u := m.requestURL(r).String(); u != test.url {
+			t.Errorf("%s: expected URL %s, found %s", test.scenario, test.url, u)
+		}
+	}
+}
+
+func TestParseLocale(t *testing.T) {
+	tests := []struct {
+		header string
+		locale string
+	}{
+		{"", ""},
+		{"*", ""},
+		{"en-US", "en-US"},
+		{"fr-CH, fr;q=0.9, en;q=0.8", "fr-CH"},
+		{"en;q=0.5, de;q=0.7", "de"},
+		{"en;q=0, de;q=0", ""},
+	}
+
+	for _, test := range tests {
+		if locale := parseLocale(test.header); locale != test.locale {
+			t.Errorf("%q: expected locale %q, found %q", test.header, test.locale, locale)
+		}
+	}
+}
+
+func TestMiddlewareHandler(t *testing.T) {
+	var msg analytics.Message
+
+	h := Middleware{}.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		msg = Enrich(r.Context(), analytics.Track{
+			UserId:  "1",
+			Event:   "Signed Up",
+			Context: &analytics.Context{UserAgent: "override"},
+		})
+	}))
+
+	r := httptest.NewRequest("GET", "http://example.com/", nil)
+	r.RemoteAddr = "1.2.3.4:5678"
+	r.Header.Set("User-Agent", "test")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	if !reflect.DeepEqual(msg, analytics.Track{
+		UserId: "1",
+		Event:  "Signed Up",
+		Context: &analytics.Context{
+			IP:        net.ParseIP("1.2.3.4"),
+			UserAgent: "override",
+			Page:      analytics.PageInfo{Path: "/", URL: "http://example.com/"},
+		},
+	}) {
+		t.Errorf("invalid message: %#v", msg)
+	}
+}
+
+func TestEnrichNoContext(t *testing.T) {
+	track := analytics.Track{UserId: "1", Event: "A"}
+
+	if msg := Enrich(context.Background(), track); !reflect.DeepEqual(msg, track) {
+		t.Errorf("invalid message: %#v", msg)
+	}
+}
+
+func TestMiddlewareEnqueueContext(t *testing.T) {
+	rec := analyticstest.NewRecorder()
+
+	h := Middleware{}.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := analytics.WithIdentity(r.Context(), "1", "")
+		rec.EnqueueContext(ctx, analytics.Track{Event: "Signed Up"})
+	}))
+
+	r := httptest.NewRequest("GET", "http://example.com/", nil)
+	r.RemoteAddr = "1.2.3.4:5678"
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	track, ok := rec.FindTrack("Signed Up")
+	if !ok {
+		t.Fatal("the track message was not recorded")
+	}
+
+	if track.UserId != "1" || track.Context == nil || !track.Context.IP.Equal(net.ParseIP("1.2.3.4")) {
+		t.Errorf("invalid track message: %#v", track)
+	}
+}
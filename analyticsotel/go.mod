@@ -0,0 +1,26 @@
+module github.com/segmentio/analytics-go/analyticsotel
+
+go 1.20
+
+require (
+	github.com/segmentio/analytics-go/v3 v3.0.1-0.20261017021445-11bb36e0a196
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/sdk v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
+)
+
+require (
+	github.com/bmizerany/assert v0.0.0-20160611221934-b7ed37b82869 // indirect
+	github.com/go-logr/logr v1.4.4 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/kr/pretty v0.3.1 // indirect
+	github.com/segmentio/backo-go v1.0.0 // indirect
+	go.opentelemetry.io/otel/metric v1.24.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
+)
+
+// The module is developed against the local copy of the analytics package,
+// programs depending on it use the version required above, which must provide
+// every API used by this module.
+replace github.com/segmentio/analytics-go/v3 => ../
@@ -0,0 +1,120 @@
+// Package analyticsotel instruments analytics clients with OpenTelemetry.
+//
+// The package lives in its own module so programs that use the analytics
+// package without OpenTelemetry don't inherit its dependencies.
+//
+// Here's a quick example of how this package is meant to be used:
+//
+//	client, err := analytics.NewWithConfig(writeKey, analytics.Config{
+//		Tracer: analyticsotel.NewTracer(analyticsotel.Config{}),
+//	})
+package analyticsotel
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/segmentio/analytics-go/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// This constant is the name of the instrumentation scope of the tracers created
+// by this package.
+const ScopeName = "github.com/segmentio/analytics-go/analyticsotel"
+
+// Attribute keys set on the spans created by tracers.
+const (
+	BatchMessageIdKey = attribute.Key("analytics.batch.message_id")
+	BatchMessagesKey  = attribute.Key("analytics.batch.messages")
+	BatchBytesKey     = attribute.Key("analytics.batch.bytes")
+	AttemptKey        = attribute.Key("analytics.upload.attempt")
+	StatusCodeKey     = attribute.Key("http.response.status_code")
+)
+
+// Instances of this type carry the different configuration options that may
+// be set when creating a tracer.
+//
+// Each field's zero-value is interpreted as using the global OpenTelemetry
+// value.
+type Config struct {
+
+	// The provider of the tracer used to create spans, set to the global tracer
+	// provider by default.
+	TracerProvider trace.TracerProvider
+
+	// The propagator used to inject the trace context in the headers of upload
+	// requests, set to the global propagator by default.
+	Propagator propagation.TextMapPropagator
+}
+
+// NewTracer returns an analytics.Tracer which creates a span for each batch
+// sent by a client, with a child span for each attempt at uploading it.
+func NewTracer(config Config) analytics.Tracer {
+	if config.TracerProvider == nil {
+		config.TracerProvider = otel.GetTracerProvider()
+	}
+
+	if config.Propagator == nil {
+		config.Propagator = otel.GetTextMapPropagator()
+	}
+
+	return tracer{
+		tracer:     config.TracerProvider.Tracer(ScopeName),
+		propagator: config.Propagator,
+	}
+}
+
+type tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+func (t tracer) StartSend(ctx context.Context, batch analytics.BatchInfo) (context.Context, func(error)) {
+	ctx, span := t.tracer.Start(ctx, "analytics.send",
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(batchAttributes(batch)...),
+	)
+
+	return ctx, func(err error) {
+		setError(span, err)
+		span.End()
+	}
+}
+
+func (t tracer) StartUpload(req *http.Request, batch analytics.BatchInfo, attempt int) (*http.Request, func(int, error)) {
+	ctx, span := t.tracer.Start(req.Context(), "analytics.upload",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(batchAttributes(batch)...),
+		trace.WithAttributes(AttemptKey.Int(attempt)),
+	)
+
+	req = req.WithContext(ctx)
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	return req, func(status int, err error) {
+		if status != 0 {
+			span.SetAttributes(StatusCodeKey.Int(status))
+		}
+		setError(span, err)
+		span.End()
+	}
+}
+
+func batchAttributes(batch analytics.BatchInfo) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		BatchMessageIdKey.String(batch.MessageId),
+		BatchMessagesKey.Int(batch.Messages),
+		BatchBytesKey.Int(batch.Bytes),
+	}
+}
+
+func setError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
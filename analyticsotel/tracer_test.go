@@ -0,0 +1,160 @@
+package analyticsotel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/segmentio/analytics-go/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type testCallback struct {
+	done chan error
+}
+
+func (c testCallback) Success(m analytics.Message)            { c.done <- nil }
+func (c testCallback) Failure(m analytics.Message, err error) { c.done <- err }
+
+func attributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestTracer(t *testing.T) {
+	var requests int32
+	traceparents := make(chan string, 2)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents <- r.Header.Get("Traceparent")
+
+		// The first attempt fails so the batch is uploaded twice.
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+	}))
+	defer server.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	callback := testCallback{make(chan error, 1)}
+
+	client, _ := analytics.NewWithConfig("h97jamjwbh", analytics.Config{
+		Endpoint:   server.URL,
+		BatchSize:  1,
+		RetryAfter: func(int) time.Duration { return time.Millisecond },
+		Callback:   callback,
+		Tracer: NewTracer(Config{
+			TracerProvider: provider,
+			Propagator:     propagation.TraceContext{},
+		}),
+	})
+
+	client.Enqueue(analytics.Track{UserId: "A", Event: "B"})
+
+	if err := <-callback.done; err != nil {
+		t.Fatal("sending the batch failed:", err)
+	}
+	client.Close()
+
+	spans := exporter.GetSpans()
+
+	if len(spans) != 3 {
+		t.Fatal("invalid number of spans:", len(spans))
+	}
+
+	send := spans[2]
+	uploads := spans[:2]
+
+	if send.Name != "analytics.send" {
+		t.Error("invalid name of the send span:", send.Name)
+	}
+
+	sendAttrs := attributes(send)
+
+	if len(sendAttrs[BatchMessageIdKey].AsString()) == 0 || sendAttrs[BatchMessagesKey].AsInt64() != 1 || sendAttrs[BatchBytesKey].AsInt64() == 0 {
+		t.Errorf("invalid attributes of the send span: %v", send.Attributes)
+	}
+
+	for i, upload := range uploads {
+		attrs := attributes(upload)
+
+		if upload.Name != "analytics.upload" {
+			t.Error("invalid name of the upload span:", upload.Name)
+		}
+
+		if upload.Parent.SpanID() != send.SpanContext.SpanID() {
+			t.Error("the upload span is not a child of the send span")
+		}
+
+		if attrs[AttemptKey].AsInt64() != int64(i) {
+			t.Error("invalid attempt index:", attrs[AttemptKey].AsInt64())
+		}
+
+		if attrs[BatchMessageIdKey] != sendAttrs[BatchMessageIdKey] {
+			t.Error("invalid batch message id:", attrs[BatchMessageIdKey].AsString())
+		}
+
+		if traceparent := <-traceparents; traceparent != "00-"+upload.SpanContext.TraceID().String()+"-"+upload.SpanContext.SpanID().String()+"-01" {
+			t.Error("invalid traceparent header:", traceparent)
+		}
+	}
+
+	if status := attributes(uploads[0])[StatusCodeKey].AsInt64(); status != 503 || uploads[0].Status.Code != codes.Error {
+		t.Errorf("invalid status of the failed upload span: %d, %v", status, uploads[0].Status)
+	}
+
+	if status := attributes(uploads[1])[StatusCodeKey].AsInt64(); status != 200 || uploads[1].Status.Code == codes.Error {
+		t.Errorf("invalid status of the successful upload span: %d, %v", status, uploads[1].Status)
+	}
+
+	if send.Status.Code == codes.Error {
+		t.Error("the send span should not be marked as failed:", send.Status)
+	}
+}
+
+func TestTracerFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	callback := testCallback{make(chan error, 1)}
+
+	client, _ := analytics.NewWithConfig("h97jamjwbh", analytics.Config{
+		Endpoint:  server.URL,
+		BatchSize: 1,
+		Callback:  callback,
+		Tracer: NewTracer(Config{
+			TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)),
+		}),
+	})
+
+	client.Enqueue(analytics.Track{UserId: "A", Event: "B"})
+
+	if err := <-callback.done; err == nil {
+		t.Fatal("sending the batch should have failed")
+	}
+	client.Close()
+
+	spans := exporter.GetSpans()
+
+	if len(spans) != 2 {
+		t.Fatal("invalid number of spans:", len(spans))
+	}
+
+	if send := spans[1]; send.Status.Code != codes.Error || len(send.Events) == 0 {
+		t.Errorf("the error was not recorded on the send span: %v", send.Status)
+	}
+}
@@ -0,0 +1,134 @@
+// Package analyticsprom exports the statistics of analytics clients as
+// Prometheus metrics.
+//
+// The package lives in its own module so programs that use the analytics
+// package without Prometheus don't inherit its dependencies.
+package analyticsprom
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/segmentio/analytics-go/v3"
+)
+
+// CollectorOpts carries the options that may be set when creating a collector.
+type CollectorOpts struct {
+
+	// The namespace of the exported metrics, set to "analytics" by default.
+	Namespace string
+
+	// Labels added to all the metrics exported by the collector, which is
+	// useful to tell apart multiple clients registered to the same registry.
+	ConstLabels prometheus.Labels
+}
+
+// Collector is an implementation of the prometheus.Collector interface which
+// exports the statistics of an analytics client.
+//
+// Statistics are read from the client every time the collector is scraped, so
+// the collector doesn't add any overhead to the client between scrapes.
+type Collector struct {
+	client analytics.Client
+
+	messages      *prometheus.Desc
+	retries       *prometheus.Desc
+	rejected      *prometheus.Desc
+	responses     *prometheus.Desc
+	queueDepth    *prometheus.Desc
+	inFlight      *prometheus.Desc
+	batchMessages *prometheus.Desc
+	batchBytes    *prometheus.Desc
+	uploadLatency *prometheus.Desc
+	messageBytes  *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+// NewCollector returns a collector which exports the statistics of the client
+// passed as argument, with the default options.
+func NewCollector(client analytics.Client) *Collector {
+	return NewCollectorWithOpts(client, CollectorOpts{})
+}
+
+// NewCollectorWithOpts returns a collector which exports the statistics of the
+// client passed as first argument, configured with the options passed as
+// second argument.
+func NewCollectorWithOpts(client analytics.Client, opts CollectorOpts) *Collector {
+	if len(opts.Namespace) == 0 {
+		opts.Namespace = "analytics"
+	}
+
+	desc := func(name string, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(opts.Namespace, "", name), help, labels, opts.ConstLabels)
+	}
+
+	return &Collector{
+		client:        client,
+		messages:      desc("messages_total", "Number of messages processed by the client, by outcome.", "outcome"),
+		retries:       desc("retries_total", "Number of times sending a batch was retried after a failure."),
+		rejected:      desc("rejected_batches_total", "Number of batches not sent because too many requests were in flight."),
+		responses:     desc("responses_total", "Number of responses received from the API, by status code.", "code"),
+		queueDepth:    desc("queue_depth", "Number of messages queued but not sent in a batch yet."),
+		inFlight:      desc("in_flight_batches", "Number of batches being sent to the API."),
+		batchMessages: desc("batch_messages", "Distribution of the number of messages per batch."),
+		batchBytes:    desc("batch_bytes", "Distribution of the size of batches in bytes, before compression."),
+		uploadLatency: desc("upload_duration_seconds", "Distribution of the duration of upload requests."),
+		messageBytes:  desc("message_bytes", "Distribution of the size of serialized messages in bytes."),
+	}
+}
+
+// Describe satisfies the prometheus.Collector interface.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.messages
+	ch <- c.retries
+	ch <- c.rejected
+	ch <- c.responses
+	ch <- c.queueDepth
+	ch <- c.inFlight
+	ch <- c.batchMessages
+	ch <- c.batchBytes
+	ch <- c.uploadLatency
+	ch <- c.messageBytes
+}
+
+// Collect satisfies the prometheus.Collector interface.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	s := c.client.Stats()
+
+	for _, m := range []struct {
+		outcome string
+		value   int64
+	}{
+		{"enqueued", s.Enqueued},
+		{"dropped", s.Dropped},
+		{"delivered", s.Delivered},
+		{"failed", s.Failed},
+		{"abandoned", s.Abandoned},
+	} {
+		ch <- prometheus.MustNewConstMetric(c.messages, prometheus.CounterValue, float64(m.value), m.outcome)
+	}
+
+	for code, n := range s.StatusCodes {
+		ch <- prometheus.MustNewConstMetric(c.responses, prometheus.CounterValue, float64(n), strconv.Itoa(code))
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.retries, prometheus.CounterValue, float64(s.Retries))
+	ch <- prometheus.MustNewConstMetric(c.rejected, prometheus.CounterValue, float64(s.Rejected))
+	ch <- prometheus.MustNewConstMetric(c.queueDepth, prometheus.GaugeValue, float64(s.QueueDepth))
+	ch <- prometheus.MustNewConstMetric(c.inFlight, prometheus.GaugeValue, float64(s.InFlight))
+	ch <- histogram(c.batchMessages, s.BatchMessages)
+	ch <- histogram(c.batchBytes, s.BatchBytes)
+	ch <- histogram(c.uploadLatency, s.UploadLatency)
+	ch <- histogram(c.messageBytes, s.MessageBytes)
+}
+
+func histogram(desc *prometheus.Desc, h analytics.Histogram) prometheus.Metric {
+	buckets := make(map[float64]uint64, len(h.Buckets))
+
+	for i, b := range h.Buckets {
+		buckets[b] = h.Counts[i]
+	}
+
+	return prometheus.MustNewConstHistogram(desc, h.Count, h.Sum, buckets)
+}
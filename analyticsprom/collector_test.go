@@ -0,0 +1,110 @@
+package analyticsprom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/segmentio/analytics-go/v3"
+	"github.com/segmentio/analytics-go/v3/analyticstest"
+)
+
+// Instances of this type are clients that return predefined statistics.
+type statsClient struct {
+	*analyticstest.Recorder
+	stats analytics.Stats
+}
+
+func (c statsClient) Stats() analytics.Stats { return c.stats }
+
+func TestCollector(t *testing.T) {
+	client := statsClient{
+		Recorder: analyticstest.NewRecorder(),
+		stats: analytics.Stats{
+			Enqueued:   10,
+			Dropped:    1,
+			Delivered:  7,
+			Failed:     2,
+			Retries:    3,
+			Rejected:   1,
+			QueueDepth: 4,
+			InFlight:   1,
+			BatchMessages: analytics.Histogram{
+				Buckets: []float64{1, 10},
+				Counts:  []uint64{1, 3},
+				Count:   3,
+				Sum:     9,
+			},
+			StatusCodes: map[int]int64{200: 2, 503: 3},
+		},
+	}
+
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(NewCollectorWithOpts(client, CollectorOpts{
+		ConstLabels: prometheus.Labels{"client": "test"},
+	}))
+
+	const ref = `
+# HELP analytics_batch_messages Distribution of the number of messages per batch.
+# TYPE analytics_batch_messages histogram
+analytics_batch_messages_bucket{client="test",le="1"} 1
+analytics_batch_messages_bucket{client="test",le="10"} 3
+analytics_batch_messages_bucket{client="test",le="+Inf"} 3
+analytics_batch_messages_sum{client="test"} 9
+analytics_batch_messages_count{client="test"} 3
+# HELP analytics_messages_total Number of messages processed by the client, by outcome.
+# TYPE analytics_messages_total counter
+analytics_messages_total{client="test",outcome="abandoned"} 0
+analytics_messages_total{client="test",outcome="delivered"} 7
+analytics_messages_total{client="test",outcome="dropped"} 1
+analytics_messages_total{client="test",outcome="enqueued"} 10
+analytics_messages_total{client="test",outcome="failed"} 2
+# HELP analytics_queue_depth Number of messages queued but not sent in a batch yet.
+# TYPE analytics_queue_depth gauge
+analytics_queue_depth{client="test"} 4
+# HELP analytics_rejected_batches_total Number of batches not sent because too many requests were in flight.
+# TYPE analytics_rejected_batches_total counter
+analytics_rejected_batches_total{client="test"} 1
+# HELP analytics_responses_total Number of responses received from the API, by status code.
+# TYPE analytics_responses_total counter
+analytics_responses_total{client="test",code="200"} 2
+analytics_responses_total{client="test",code="503"} 3
+# HELP analytics_retries_total Number of times sending a batch was retried after a failure.
+# TYPE analytics_retries_total counter
+analytics_retries_total{client="test"} 3
+`
+
+	err := testutil.GatherAndCompare(registry, strings.NewReader(ref),
+		"analytics_batch_messages",
+		"analytics_messages_total",
+		"analytics_queue_depth",
+		"analytics_rejected_batches_total",
+		"analytics_responses_total",
+		"analytics_retries_total",
+	)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCollectorClient(t *testing.T) {
+	client, _ := analytics.NewWithConfig("h97jamjwbh", analytics.Config{
+		Endpoint: "http://localhost:0",
+	})
+	defer client.Close()
+
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(NewCollector(client))
+
+	n, err := testutil.GatherAndCount(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// No responses were received so no status code is exported, all other
+	// metrics always are.
+	if n != 13 {
+		t.Error("invalid number of metrics exported:", n)
+	}
+}
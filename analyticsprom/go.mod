@@ -0,0 +1,29 @@
+module github.com/segmentio/analytics-go/analyticsprom
+
+go 1.20
+
+require (
+	github.com/prometheus/client_golang v1.20.5
+	github.com/segmentio/analytics-go/v3 v3.0.1-0.20261017021445-11bb36e0a196
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/bmizerany/assert v0.0.0-20160611221934-b7ed37b82869 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/kylelemons/godebug v1.1.0 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/prometheus/client_model v0.6.1 // indirect
+	github.com/prometheus/common v0.59.1 // indirect
+	github.com/prometheus/procfs v0.15.1 // indirect
+	github.com/segmentio/backo-go v1.0.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
+	google.golang.org/protobuf v1.34.2 // indirect
+)
+
+// The module is developed against the local copy of the analytics package,
+// programs depending on it use the version required above, which must provide
+// every API used by this module.
+replace github.com/segmentio/analytics-go/v3 => ../
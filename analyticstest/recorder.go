@@ -0,0 +1,234 @@
+// Package analyticstest provides utilities to test programs that send messages
+// with the analytics package.
+package analyticstest
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/segmentio/analytics-go/v3"
+)
+
+var _ analytics.Client = (*Recorder)(nil)
+
+// Recorder is an implementation of the analytics.Client interface which records
+// the messages it receives instead of sending them.
+// Messages are validated and get the same defaults as with the clients of the
+// analytics package (type, message id and timestamp), so tests can make
+// assertions on the exact messages that would have been sent.
+//
+// Here's a quick example of how this type is meant to be used:
+//
+//	rec := analyticstest.NewRecorder()
+//	signup(rec, "0123456789")
+//	rec.AssertTracked(t, "Signed Up", analytics.Properties{"plan": "pro"})
+//
+// Recorder values are safe to use concurrently from multiple goroutines.
+type Recorder struct {
+	mutex  sync.Mutex
+	msgs   []analytics.Message
+	closed bool
+}
+
+// NewRecorder returns a new recorder with no messages.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Enqueue records the message passed as argument. It returns an error if the
+// message was invalid or if the recorder was closed.
+func (r *Recorder) Enqueue(msg analytics.Message) error {
+	return r.EnqueueContext(context.Background(), msg)
+}
+
+// EnqueueContext records the message passed as argument, like Enqueue. The
+// message gets the identity and analytics context carried by ctx like with the
+// clients of the analytics package, see analytics.Enrich.
+func (r *Recorder) EnqueueContext(ctx context.Context, msg analytics.Message) error {
+	msg, err := analytics.Prepare(analytics.Enrich(ctx, msg))
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return analytics.ErrClosed
+	}
+
+	r.msgs = append(r.msgs, msg)
+	return nil
+}
+
+// Flush does nothing since messages are recorded as soon as they are queued,
+// it only returns an error if the recorder was closed.
+func (r *Recorder) Flush(ctx context.Context) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return analytics.ErrClosed
+	}
+	return nil
+}
+
+// Close marks the recorder as closed, messages queued afterwards are rejected
+// with analytics.ErrClosed. Recorded messages remain available.
+func (r *Recorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return analytics.ErrClosed
+	}
+
+	r.closed = true
+	return nil
+}
+
+// CloseContext closes the recorder like Close.
+func (r *Recorder) CloseContext(ctx context.Context) error {
+	return r.Close()
+}
+
+// Stats returns statistics where all recorded messages are counted as enqueued
+// and delivered, since recording a message never fails.
+func (r *Recorder) Stats() analytics.Stats {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	n := int64(len(r.msgs))
+	return analytics.Stats{
+		Enqueued:  n,
+		Delivered: n,
+	}
+}
+
+// Messages returns all the messages recorded so far, in the order they were
+// queued.
+func (r *Recorder) Messages() []analytics.Message {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]analytics.Message(nil), r.msgs...)
+}
+
+// Aliases returns the alias messages recorded so far.
+func (r *Recorder) Aliases() (msgs []analytics.Alias) {
+	for _, m := range r.Messages() {
+		if a, ok := m.(analytics.Alias); ok {
+			msgs = append(msgs, a)
+		}
+	}
+	return
+}
+
+// Groups returns the group messages recorded so far.
+func (r *Recorder) Groups() (msgs []analytics.Group) {
+	for _, m := range r.Messages() {
+		if g, ok := m.(analytics.Group); ok {
+			msgs = append(msgs, g)
+		}
+	}
+	return
+}
+
+// Identifies returns the identify messages recorded so far.
+func (r *Recorder) Identifies() (msgs []analytics.Identify) {
+	for _, m := range r.Messages() {
+		if i, ok := m.(analytics.Identify); ok {
+			msgs = append(msgs, i)
+		}
+	}
+	return
+}
+
+// Pages returns the page messages recorded so far.
+func (r *Recorder) Pages() (msgs []analytics.Page) {
+	for _, m := range r.Messages() {
+		if p, ok := m.(analytics.Page); ok {
+			msgs = append(msgs, p)
+		}
+	}
+	return
+}
+
+// Screens returns the screen messages recorded so far.
+func (r *Recorder) Screens() (msgs []analytics.Screen) {
+	for _, m := range r.Messages() {
+		if s, ok := m.(analytics.Screen); ok {
+			msgs = append(msgs, s)
+		}
+	}
+	return
+}
+
+// Tracks returns the track messages recorded so far.
+func (r *Recorder) Tracks() (msgs []analytics.Track) {
+	for _, m := range r.Messages() {
+		if t, ok := m.(analytics.Track); ok {
+			msgs = append(msgs, t)
+		}
+	}
+	return
+}
+
+// FindTrack returns the first track message recorded for the event passed as
+// argument, the second return value is false if there was none.
+func (r *Recorder) FindTrack(event string) (analytics.Track, bool) {
+	for _, t := range r.Tracks() {
+		if t.Event == event {
+			return t, true
+		}
+	}
+	return analytics.Track{}, false
+}
+
+// AssertTracked reports a test error if no track message was recorded for the
+// event passed as argument with at least the given properties. Properties of
+// the recorded messages that aren't part of props are ignored.
+// The method returns true if the assertion succeeded.
+func (r *Recorder) AssertTracked(t testing.TB, event string, props analytics.Properties) bool {
+	t.Helper()
+
+	tracks := r.Tracks()
+	found := false
+
+	for _, track := range tracks {
+		if track.Event != event {
+			continue
+		}
+
+		if hasProperties(track.Properties, props) {
+			return true
+		}
+
+		found = true
+	}
+
+	if found {
+		t.Errorf("analyticstest: %q was tracked but not with properties %v, recorded tracks: %v", event, props, tracks)
+	} else {
+		t.Errorf("analyticstest: %q was not tracked, recorded tracks: %v", event, tracks)
+	}
+
+	return false
+}
+
+// Reset discards all the messages recorded so far.
+func (r *Recorder) Reset() {
+	r.mutex.Lock()
+	r.msgs = nil
+	r.mutex.Unlock()
+}
+
+func hasProperties(props analytics.Properties, subset analytics.Properties) bool {
+	for name, value := range subset {
+		if v, ok := props[name]; !ok || !reflect.DeepEqual(v, value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,157 @@
+package analyticstest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/segmentio/analytics-go/v3"
+)
+
+// Instances of this type are used to capture the errors reported by assertions
+// that are expected to fail.
+type testRecorderT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *testRecorderT) Helper() {}
+
+func (t *testRecorderT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRecorderDefaults(t *testing.T) {
+	rec := NewRecorder()
+
+	if err := rec.Enqueue(&analytics.Track{UserId: "1", Event: "Download"}); err != nil {
+		t.Fatal(err)
+	}
+
+	track, ok := rec.FindTrack("Download")
+	if !ok {
+		t.Fatal("the track message was not recorded")
+	}
+
+	if track.Type != "track" {
+		t.Error("invalid message type:", track.Type)
+	}
+
+	if len(track.MessageId) == 0 {
+		t.Error("no message id was generated")
+	}
+
+	if track.Timestamp.IsZero() {
+		t.Error("no timestamp was set")
+	}
+}
+
+func TestRecorderKeepsMessageIdAndTimestamp(t *testing.T) {
+	rec := NewRecorder()
+	ts := time.Date(2015, time.July, 10, 23, 0, 0, 0, time.UTC)
+
+	rec.Enqueue(analytics.Identify{UserId: "1", MessageId: "A", Timestamp: ts})
+
+	if ids := rec.Identifies(); len(ids) != 1 {
+		t.Fatal("invalid number of identify messages recorded:", len(ids))
+
+	} else if ids[0].MessageId != "A" || !ids[0].Timestamp.Equal(ts) {
+		t.Error("the message id and timestamp should not have been overwritten:", ids[0])
+	}
+}
+
+func TestRecorderEnqueueContext(t *testing.T) {
+	rec := NewRecorder()
+
+	ctx := analytics.WithIdentity(context.Background(), "1", "")
+	ctx = analytics.WithAnalyticsContext(ctx, &analytics.Context{UserAgent: "test"})
+
+	if err := rec.EnqueueContext(ctx, analytics.Track{Event: "Download"}); err != nil {
+		t.Fatal(err)
+	}
+
+	track, ok := rec.FindTrack("Download")
+	if !ok {
+		t.Fatal("the track message was not recorded")
+	}
+
+	if track.UserId != "1" || track.Context == nil || track.Context.UserAgent != "test" {
+		t.Errorf("the message did not get the identity and context carried by the context: %#v", track)
+	}
+}
+
+func TestRecorderValidation(t *testing.T) {
+	rec := NewRecorder()
+
+	if err := rec.Enqueue(analytics.Track{Event: "Download"}); err == nil {
+		t.Error("no error returned when recording an invalid message")
+	}
+
+	if n := len(rec.Messages()); n != 0 {
+		t.Error("invalid messages should not be recorded:", n)
+	}
+}
+
+func TestRecorderClose(t *testing.T) {
+	rec := NewRecorder()
+	rec.Close()
+
+	if err := rec.Enqueue(analytics.Track{UserId: "1", Event: "Download"}); err != analytics.ErrClosed {
+		t.Error("recording a message after the recorder was closed should return ErrClosed:", err)
+	}
+
+	if err := rec.Close(); err != analytics.ErrClosed {
+		t.Error("closing a recorder twice should return ErrClosed:", err)
+	}
+}
+
+func TestRecorderAssertTracked(t *testing.T) {
+	rec := NewRecorder()
+	rec.Enqueue(analytics.Track{
+		UserId:     "1",
+		Event:      "Signed Up",
+		Properties: analytics.NewProperties().Set("plan", "pro").Set("trial", true),
+	})
+
+	if !rec.AssertTracked(t, "Signed Up", analytics.Properties{"plan": "pro"}) {
+		t.Error("the assertion should have succeeded")
+	}
+
+	tests := map[string]struct {
+		event string
+		props analytics.Properties
+	}{
+		"event":    {"Signed In", nil},
+		"property": {"Signed Up", analytics.Properties{"plan": "free"}},
+	}
+
+	for name, test := range tests {
+		tt := &testRecorderT{TB: t}
+
+		if rec.AssertTracked(tt, test.event, test.props) || len(tt.errors) != 1 {
+			t.Errorf("%s: the assertion should have failed: %v", name, tt.errors)
+		}
+	}
+}
+
+func TestRecorderReset(t *testing.T) {
+	rec := NewRecorder()
+	rec.Enqueue(analytics.Page{UserId: "1", Name: "Home"})
+	rec.Enqueue(analytics.Screen{UserId: "1", Name: "Home"})
+	rec.Reset()
+
+	if n := len(rec.Messages()); n != 0 {
+		t.Error("messages should have been discarded:", n)
+	}
+}
+
+func TestRecorderStats(t *testing.T) {
+	rec := NewRecorder()
+	rec.Enqueue(analytics.Track{UserId: "1", Event: "Download"})
+	rec.Enqueue(analytics.Identify{UserId: "1"})
+
+	if s := rec.Stats(); s.Enqueued != 2 || s.Delivered != 2 {
+		t.Errorf("invalid recorder stats: %#v", s)
+	}
+}
@@ -0,0 +1,340 @@
+package analyticstest
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/analytics-go/v3"
+)
+
+const (
+	// The limits enforced by the server, they match the limits enforced by
+	// analytics clients so the server catches clients sending larger payloads.
+	maxBatchBytes   = 500000
+	maxMessageBytes = 32000
+)
+
+// Server is a stand-in for the tracking API, it implements the /v1/batch
+// endpoint used by analytics clients as well as the single message endpoints
+// (/v1/track, /v1/identify, ...), and records the messages it receives.
+//
+// The server is meant to be used in integration tests, for example:
+//
+//	server := analyticstest.NewServer("my-write-key")
+//	defer server.Close()
+//
+//	client, _ := analytics.NewWithConfig("my-write-key", analytics.Config{
+//		Endpoint: server.URL,
+//	})
+//	...
+//	client.Flush(ctx)
+//	server.Messages()
+//
+// Faults (error responses and latency) can be injected to test how programs
+// behave when the API is unavailable.
+type Server struct {
+	*httptest.Server
+
+	writeKeys map[string]bool
+
+	mutex   sync.Mutex
+	batches []Batch
+	faults  []Fault
+	latency time.Duration
+}
+
+// Batch represents a batch of messages received by a server. Requests to the
+// single message endpoints are recorded as batches of one message.
+type Batch struct {
+	MessageId string                 `json:"messageId,omitempty"`
+	SentAt    time.Time              `json:"sentAt,omitempty"`
+	Context   map[string]interface{} `json:"context,omitempty"`
+	Messages  []Message              `json:"batch"`
+
+	// The write key that the batch was sent with.
+	WriteKey string `json:"-"`
+}
+
+// Message represents a message received by a server, decoded from its JSON
+// representation.
+// The type implements analytics.FieldGetter so the messages can be validated
+// with analytics.ValidateFields.
+type Message map[string]interface{}
+
+var _ analytics.FieldGetter = Message(nil)
+
+// GetField returns the value of a top-level field of the message.
+func (m Message) GetField(field string) (interface{}, bool) {
+	v, ok := m[field]
+	return v, ok
+}
+
+// Fault describes an error that a server responds with instead of processing a
+// request.
+type Fault struct {
+
+	// The status code of the response, defaults to 500 when only a latency is
+	// set.
+	StatusCode int
+
+	// Headers set on the response, for example `Retry-After`.
+	Header http.Header
+
+	// How long the server waits before sending the response.
+	Latency time.Duration
+}
+
+// NewServer starts a server that accepts requests authenticated with any of the
+// write keys passed as arguments, or with any non-empty write key if none were
+// given. The server must be closed when it's not used anymore.
+func NewServer(writeKeys ...string) *Server {
+	s := &Server{
+		writeKeys: make(map[string]bool, len(writeKeys)),
+	}
+
+	for _, key := range writeKeys {
+		s.writeKeys[key] = true
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/batch", s.handleBatch)
+
+	for _, typ := range []string{"alias", "group", "identify", "page", "screen", "track"} {
+		mux.HandleFunc("/v1/"+typ, s.handleMessage(typ))
+	}
+
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// InjectFaults queues faults that the server responds with, one per request,
+// before handling requests normally again.
+func (s *Server) InjectFaults(faults ...Fault) {
+	s.mutex.Lock()
+	s.faults = append(s.faults, faults...)
+	s.mutex.Unlock()
+}
+
+// SetLatency sets a delay that the server waits for before handling each
+// request.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mutex.Lock()
+	s.latency = d
+	s.mutex.Unlock()
+}
+
+// Batches returns the batches received so far, in the order they were received.
+func (s *Server) Batches() []Batch {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Batch(nil), s.batches...)
+}
+
+// Messages returns the messages of all the batches received so far.
+func (s *Server) Messages() (msgs []Message) {
+	for _, b := range s.Batches() {
+		msgs = append(msgs, b.Messages...)
+	}
+	return
+}
+
+// Reset discards the batches received so far as well as pending faults.
+func (s *Server) Reset() {
+	s.mutex.Lock()
+	s.batches = nil
+	s.faults = nil
+	s.mutex.Unlock()
+}
+
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	var batch Batch
+
+	if !s.handle(w, r, maxBatchBytes, &batch) {
+		return
+	}
+
+	for _, m := range batch.Messages {
+		if !s.validate(w, m) {
+			return
+		}
+	}
+
+	batch.WriteKey, _, _ = r.BasicAuth()
+	s.record(w, batch)
+}
+
+func (s *Server) handleMessage(typ string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var msg Message
+
+		if !s.handle(w, r, maxMessageBytes, &msg) {
+			return
+		}
+
+		// A body of null decodes to a nil map, which isn't a message.
+		if msg == nil {
+			respond(w, http.StatusBadRequest, "invalid message: null")
+			return
+		}
+
+		msg["type"] = typ
+
+		if !s.validate(w, msg) {
+			return
+		}
+
+		key, _, _ := r.BasicAuth()
+		s.record(w, Batch{
+			Messages: []Message{msg},
+			WriteKey: key,
+		})
+	}
+}
+
+// Applies faults, checks the request method, authentication and size, then
+// decodes the request body into v. The method returns false if it already
+// responded to the request.
+func (s *Server) handle(w http.ResponseWriter, r *http.Request, maxBytes int, v interface{}) bool {
+	if s.fault(w) {
+		return false
+	}
+
+	if r.Method != "POST" {
+		respond(w, http.StatusMethodNotAllowed, "method not allowed: %s", r.Method)
+		return false
+	}
+
+	if key, _, ok := r.BasicAuth(); !ok || len(key) == 0 || (len(s.writeKeys) != 0 && !s.writeKeys[key]) {
+		respond(w, http.StatusUnauthorized, "invalid write key")
+		return false
+	}
+
+	var body io.Reader = r.Body
+
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		z, err := gzip.NewReader(r.Body)
+		if err != nil {
+			respond(w, http.StatusBadRequest, "invalid gzip body: %s", err)
+			return false
+		}
+		defer z.Close()
+		body = z
+	}
+
+	// Reading one byte past the limit is enough to know it was exceeded.
+	b, err := ioutil.ReadAll(io.LimitReader(body, int64(maxBytes)+1))
+	if err != nil {
+		respond(w, http.StatusBadRequest, "reading body: %s", err)
+		return false
+	}
+
+	if len(b) > maxBytes {
+		respond(w, http.StatusRequestEntityTooLarge, "the body exceeds the maximum size of %d bytes", maxBytes)
+		return false
+	}
+
+	if _, ok := v.(*Batch); ok {
+		var raw struct {
+			Messages []json.RawMessage `json:"batch"`
+		}
+
+		if err := json.Unmarshal(b, &raw); err != nil {
+			respond(w, http.StatusBadRequest, "invalid JSON body: %s", err)
+			return false
+		}
+
+		for _, m := range raw.Messages {
+			if len(m) > maxMessageBytes {
+				respond(w, http.StatusRequestEntityTooLarge, "a message exceeds the maximum size of %d bytes", maxMessageBytes)
+				return false
+			}
+		}
+	}
+
+	if err := json.Unmarshal(b, v); err != nil {
+		respond(w, http.StatusBadRequest, "invalid JSON body: %s", err)
+		return false
+	}
+
+	return true
+}
+
+func (s *Server) validate(w http.ResponseWriter, msg Message) bool {
+	if msg == nil {
+		respond(w, http.StatusBadRequest, "invalid message: null")
+		return false
+	}
+
+	if err := analytics.ValidateFields(msg); err != nil {
+		respond(w, http.StatusBadRequest, "invalid message: %s", err)
+		return false
+	}
+	return true
+}
+
+func (s *Server) record(w http.ResponseWriter, batch Batch) {
+	s.mutex.Lock()
+	s.batches = append(s.batches, batch)
+	s.mutex.Unlock()
+	respond(w, http.StatusOK, "")
+}
+
+// Waits for the configured latency and responds with the next fault, if any.
+// The method returns true if a fault was sent.
+func (s *Server) fault(w http.ResponseWriter) bool {
+	s.mutex.Lock()
+	latency := s.latency
+	var f *Fault
+	if len(s.faults) != 0 {
+		f, s.faults = &s.faults[0], s.faults[1:]
+	}
+	s.mutex.Unlock()
+
+	time.Sleep(latency)
+
+	if f == nil {
+		return false
+	}
+
+	time.Sleep(f.Latency)
+
+	for name, values := range f.Header {
+		w.Header()[name] = values
+	}
+
+	code := f.StatusCode
+	if code == 0 {
+		code = http.StatusInternalServerError
+	}
+
+	respond(w, code, "injected fault")
+	return true
+}
+
+func respond(w http.ResponseWriter, code int, format string, args ...interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if code < 300 {
+		io.WriteString(w, `{"success":true}`)
+		return
+	}
+
+	b, _ := json.Marshal(struct {
+		Success bool   `json:"success"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}{
+		Code:    strings.ToLower(strings.Replace(http.StatusText(code), " ", "_", -1)),
+		Message: fmt.Sprintf(format, args...),
+	})
+	w.Write(b)
+}
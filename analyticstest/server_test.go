@@ -0,0 +1,160 @@
+package analyticstest
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/analytics-go/v3"
+)
+
+func newTestClient(t *testing.T, server *Server, writeKey string, config analytics.Config) analytics.Client {
+	config.Endpoint = server.URL
+	config.Logger = analytics.StdLogger(log.New(ioutil.Discard, "", 0))
+	config.Interval = time.Hour
+	config.RetryAfter = func(int) time.Duration { return time.Millisecond }
+
+	client, err := analytics.NewWithConfig(writeKey, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func post(t *testing.T, server *Server, path string, writeKey string, body string) *http.Response {
+	req, err := http.NewRequest("POST", server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth(writeKey, "")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	return res
+}
+
+func TestServerBatch(t *testing.T) {
+	server := NewServer("h97jamjwbh")
+	defer server.Close()
+
+	for _, compression := range []analytics.Compression{analytics.CompressionNone, analytics.CompressionGzip} {
+		server.Reset()
+
+		client := newTestClient(t, server, "h97jamjwbh", analytics.Config{
+			Compression: compression,
+		})
+
+		client.Enqueue(analytics.Track{UserId: "1", Event: "Download"})
+		client.Enqueue(analytics.Identify{UserId: "1"})
+		client.Close()
+
+		batches := server.Batches()
+
+		if len(batches) != 1 {
+			t.Fatalf("invalid number of batches received: %d", len(batches))
+		}
+
+		if key := batches[0].WriteKey; key != "h97jamjwbh" {
+			t.Error("invalid write key recorded:", key)
+		}
+
+		msgs := batches[0].Messages
+
+		if len(msgs) != 2 || msgs[0]["event"] != "Download" || msgs[1]["type"] != "identify" {
+			t.Errorf("invalid messages received: %v", msgs)
+		}
+	}
+}
+
+func TestServerSingleMessage(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	if res := post(t, server, "/v1/track", "key", `{"userId":"1","event":"Download"}`); res.StatusCode != http.StatusOK {
+		t.Fatal("invalid status code:", res.StatusCode)
+	}
+
+	if msgs := server.Messages(); len(msgs) != 1 || msgs[0]["type"] != "track" {
+		t.Errorf("invalid messages received: %v", msgs)
+	}
+}
+
+func TestServerRejects(t *testing.T) {
+	server := NewServer("h97jamjwbh")
+	defer server.Close()
+
+	large := `{"batch":[{"type":"track","userId":"1","event":"` + strings.Repeat("A", maxMessageBytes) + `"}]}`
+
+	tests := map[string]struct {
+		path string
+		key  string
+		body string
+		code int
+	}{
+		"write key":      {"/v1/batch", "nope", `{"batch":[]}`, http.StatusUnauthorized},
+		"json":           {"/v1/batch", "h97jamjwbh", `{`, http.StatusBadRequest},
+		"invalid":        {"/v1/batch", "h97jamjwbh", `{"batch":[{"type":"track","event":"Download"}]}`, http.StatusBadRequest},
+		"message size":   {"/v1/batch", "h97jamjwbh", large, http.StatusRequestEntityTooLarge},
+		"batch size":     {"/v1/batch", "h97jamjwbh", strings.Repeat(" ", maxBatchBytes+1), http.StatusRequestEntityTooLarge},
+		"single size":    {"/v1/track", "h97jamjwbh", large, http.StatusRequestEntityTooLarge},
+		"client message": {"/v1/track", "h97jamjwbh", `{"type":"track","userId":"1","event":"` + strings.Repeat("A", 32000) + `"}`, http.StatusRequestEntityTooLarge},
+		"client batch":   {"/v1/batch", "h97jamjwbh", strings.Repeat(" ", 500001), http.StatusRequestEntityTooLarge},
+		"single invalid": {"/v1/identify", "h97jamjwbh", `{}`, http.StatusBadRequest},
+		"single null":    {"/v1/track", "h97jamjwbh", `null`, http.StatusBadRequest},
+		"batch null":     {"/v1/batch", "h97jamjwbh", `{"batch":[null]}`, http.StatusBadRequest},
+	}
+
+	for name, test := range tests {
+		if res := post(t, server, test.path, test.key, test.body); res.StatusCode != test.code {
+			t.Errorf("%s: invalid status code: %d", name, res.StatusCode)
+		}
+	}
+
+	if n := len(server.Batches()); n != 0 {
+		t.Error("rejected requests should not be recorded:", n)
+	}
+}
+
+func TestServerFaults(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	server.InjectFaults(
+		Fault{StatusCode: http.StatusTooManyRequests},
+		Fault{StatusCode: http.StatusServiceUnavailable},
+	)
+
+	client := newTestClient(t, server, "key", analytics.Config{})
+	client.Enqueue(analytics.Track{UserId: "1", Event: "Download"})
+
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+
+	// The client retries until the faults are exhausted.
+	if n := len(server.Messages()); n != 1 {
+		t.Error("invalid number of messages received:", n)
+	}
+}
+
+func TestServerLatency(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	server.SetLatency(20 * time.Millisecond)
+	t0 := time.Now()
+
+	post(t, server, "/v1/track", "key", `{"userId":"1","event":"Download"}`)
+
+	if d := time.Now().Sub(t0); d < 20*time.Millisecond {
+		t.Error("the server did not wait for the configured latency:", d)
+	}
+}
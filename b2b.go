@@ -0,0 +1,185 @@
+package analytics
+
+import "time"
+
+// This file defines the events of the B2B SaaS spec as described in
+// https://segment.com/docs/connections/spec/b2b-saas/
+//
+// The spec requires all B2B events to be linked to the account they happened
+// in, so each event has a `GroupId` field which is set to `context.groupId` on
+// the track message.
+
+// This type represents the properties of the Account Created event.
+type AccountCreated struct {
+	GroupId     string
+	AccountName string
+}
+
+// This type represents the properties of the Account Deleted event.
+type AccountDeleted struct {
+	GroupId     string
+	AccountName string
+}
+
+// This type represents the properties of the Signed Up event.
+type SignedUp struct {
+	GroupId   string
+	Type      string
+	FirstName string
+	LastName  string
+	Email     string
+	Phone     string
+	Username  string
+	Title     string
+}
+
+// This type represents the properties of the Signed In event.
+type SignedIn struct {
+	GroupId  string
+	Username string
+}
+
+// This type represents the properties of the trial events.
+type Trial struct {
+	GroupId       string
+	TrialStart    time.Time
+	TrialEnd      time.Time
+	TrialPlanName string
+}
+
+// This type represents the properties of the Trial Started event.
+type TrialStarted Trial
+
+// This type represents the properties of the Trial Ended event.
+type TrialEnded Trial
+
+// This type represents the properties of the Invite Sent event.
+type InviteSent struct {
+	GroupId          string
+	InviteeEmail     string
+	InviteeFirstName string
+	InviteeLastName  string
+	InviteeRole      string
+}
+
+// This type represents the properties of the Account Added User event.
+type AccountAddedUser struct {
+	GroupId string
+	Role    string
+}
+
+func (e AccountCreated) Validate() error {
+	return validateGroupId("analytics.AccountCreated", e.GroupId)
+}
+
+func (e AccountCreated) Apply(msg *Track) {
+	msg.Event = "Account Created"
+	msg.Context.GroupID = e.GroupId
+	msg.Properties.setString("account_name", e.AccountName)
+}
+
+func (e AccountDeleted) Validate() error {
+	return validateGroupId("analytics.AccountDeleted", e.GroupId)
+}
+
+func (e AccountDeleted) Apply(msg *Track) {
+	msg.Event = "Account Deleted"
+	msg.Context.GroupID = e.GroupId
+	msg.Properties.setString("account_name", e.AccountName)
+}
+
+func (e SignedUp) Validate() error {
+	return validateGroupId("analytics.SignedUp", e.GroupId)
+}
+
+func (e SignedUp) Apply(msg *Track) {
+	msg.Event = "Signed Up"
+	msg.Context.GroupID = e.GroupId
+	msg.Properties.
+		setString("type", e.Type).
+		setString("first_name", e.FirstName).
+		setString("last_name", e.LastName).
+		setString("email", e.Email).
+		setString("phone", e.Phone).
+		setString("username", e.Username).
+		setString("title", e.Title)
+}
+
+func (e SignedIn) Validate() error {
+	return validateGroupId("analytics.SignedIn", e.GroupId)
+}
+
+func (e SignedIn) Apply(msg *Track) {
+	msg.Event = "Signed In"
+	msg.Context.GroupID = e.GroupId
+	msg.Properties.setString("username", e.Username)
+}
+
+func (e TrialStarted) Validate() error {
+	return validateGroupId("analytics.TrialStarted", e.GroupId)
+}
+
+func (e TrialStarted) Apply(msg *Track) {
+	msg.Event = "Trial Started"
+	msg.Context.GroupID = e.GroupId
+	msg.Properties.setTrial(Trial(e))
+}
+
+func (e TrialEnded) Validate() error {
+	return validateGroupId("analytics.TrialEnded", e.GroupId)
+}
+
+func (e TrialEnded) Apply(msg *Track) {
+	msg.Event = "Trial Ended"
+	msg.Context.GroupID = e.GroupId
+	msg.Properties.setTrial(Trial(e))
+}
+
+func (e InviteSent) Validate() error {
+	return validateGroupId("analytics.InviteSent", e.GroupId)
+}
+
+func (e InviteSent) Apply(msg *Track) {
+	msg.Event = "Invite Sent"
+	msg.Context.GroupID = e.GroupId
+	msg.Properties.
+		setString("invitee_email", e.InviteeEmail).
+		setString("invitee_first_name", e.InviteeFirstName).
+		setString("invitee_last_name", e.InviteeLastName).
+		setString("invitee_role", e.InviteeRole)
+}
+
+func (e AccountAddedUser) Validate() error {
+	return validateGroupId("analytics.AccountAddedUser", e.GroupId)
+}
+
+func (e AccountAddedUser) Apply(msg *Track) {
+	msg.Event = "Account Added User"
+	msg.Context.GroupID = e.GroupId
+	msg.Properties.setString("role", e.Role)
+}
+
+func validateGroupId(typ string, groupId string) error {
+	if len(groupId) == 0 {
+		return FieldError{
+			Type:  typ,
+			Name:  "GroupId",
+			Value: groupId,
+		}
+	}
+	return nil
+}
+
+func (p Properties) setTime(name string, value time.Time) Properties {
+	if !value.IsZero() {
+		p[name] = value
+	}
+	return p
+}
+
+func (p Properties) setTrial(trial Trial) Properties {
+	return p.
+		setTime("trial_start_date", trial.TrialStart).
+		setTime("trial_end_date", trial.TrialEnd).
+		setString("trial_plan_name", trial.TrialPlanName)
+}
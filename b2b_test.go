@@ -0,0 +1,84 @@
+package analytics
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestB2BEvents(t *testing.T) {
+	start := time.Date(2019, 8, 13, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		event SpecEvent
+		ref   string
+	}{
+		{
+			event: AccountCreated{GroupId: "acme", AccountName: "Acme Inc"},
+			ref:   `{"event":"Account Created","groupId":"acme","properties":{"account_name":"Acme Inc"}}`,
+		},
+		{
+			event: AccountDeleted{GroupId: "acme", AccountName: "Acme Inc"},
+			ref:   `{"event":"Account Deleted","groupId":"acme","properties":{"account_name":"Acme Inc"}}`,
+		},
+		{
+			event: SignedUp{GroupId: "acme", Type: "organic", FirstName: "Peter", Email: "peter@example.com"},
+			ref:   `{"event":"Signed Up","groupId":"acme","properties":{"email":"peter@example.com","first_name":"Peter","type":"organic"}}`,
+		},
+		{
+			event: SignedIn{GroupId: "acme", Username: "pgibbons"},
+			ref:   `{"event":"Signed In","groupId":"acme","properties":{"username":"pgibbons"}}`,
+		},
+		{
+			event: TrialStarted{GroupId: "acme", TrialStart: start, TrialEnd: start.AddDate(0, 0, 14), TrialPlanName: "Business"},
+			ref:   `{"event":"Trial Started","groupId":"acme","properties":{"trial_end_date":"2019-08-27T00:00:00Z","trial_plan_name":"Business","trial_start_date":"2019-08-13T00:00:00Z"}}`,
+		},
+		{
+			event: TrialEnded{GroupId: "acme", TrialPlanName: "Business"},
+			ref:   `{"event":"Trial Ended","groupId":"acme","properties":{"trial_plan_name":"Business"}}`,
+		},
+		{
+			event: InviteSent{GroupId: "acme", InviteeEmail: "bill@example.com", InviteeRole: "Owner"},
+			ref:   `{"event":"Invite Sent","groupId":"acme","properties":{"invitee_email":"bill@example.com","invitee_role":"Owner"}}`,
+		},
+		{
+			event: AccountAddedUser{GroupId: "acme", Role: "Owner"},
+			ref:   `{"event":"Account Added User","groupId":"acme","properties":{"role":"Owner"}}`,
+		},
+	}
+
+	for _, test := range tests {
+		track, err := SpecTrack(Track{}, test.event)
+		if err != nil {
+			t.Errorf("%T: %s", test.event, err)
+			continue
+		}
+
+		b, _ := json.Marshal(struct {
+			Event      string     `json:"event"`
+			GroupId    string     `json:"groupId"`
+			Properties Properties `json:"properties"`
+		}{track.Event, track.Context.GroupID, track.Properties})
+
+		if s := string(b); s != test.ref {
+			t.Errorf("%T: invalid track message:\n- expected %s\n- found: %s", test.event, test.ref, s)
+		}
+	}
+}
+
+func TestB2BEventsValidate(t *testing.T) {
+	for _, event := range []SpecEvent{
+		AccountCreated{},
+		AccountDeleted{},
+		SignedUp{},
+		SignedIn{},
+		TrialStarted{},
+		TrialEnded{},
+		InviteSent{},
+		AccountAddedUser{},
+	} {
+		if err, ok := event.Validate().(FieldError); !ok || err.Name != "GroupId" {
+			t.Errorf("%T: invalid validation error: %v", event, err)
+		}
+	}
+}
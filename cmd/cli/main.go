@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"sync/atomic"
 
 	"github.com/segmentio/analytics-go/v3"
 	"github.com/segmentio/conf"
@@ -19,9 +21,15 @@ func main() {
 		Event      string `conf:"event"      help:"Name of the track event"`
 		Properties string `conf:"properties" help:"Metadata associated with an event, page or screen call"`
 		Name       string `conf:"name"       help:"Name of the page/screen"`
+		Replay     string `conf:"replay"     help:"Dead-letter file or directory of failed batches to send again"`
 	}
 	conf.Load(&config)
 
+	if len(config.Replay) != 0 {
+		replay(config.WriteKey, config.Replay)
+		return
+	}
+
 	callback := callback(make(chan error, 1))
 
 	client, err := analytics.NewWithConfig(config.WriteKey, analytics.Config{
@@ -70,6 +78,35 @@ func main() {
 	}
 }
 
+// replay sends again the messages of the failed batches found at path, then
+// waits for them to be uploaded before exiting.
+func replay(writeKey string, path string) {
+	failures := &failureCounter{}
+
+	client, err := analytics.NewWithConfig(writeKey, analytics.Config{
+		Callback: failures,
+	})
+	if err != nil {
+		fmt.Println("could not initialize analytics client", err)
+		os.Exit(1)
+	}
+
+	n, err := analytics.ReplayDeadLetters(context.Background(), client, path)
+	client.Close()
+
+	if err != nil {
+		fmt.Println("could not replay dead letters", err)
+		os.Exit(1)
+	}
+
+	if failed := atomic.LoadInt64(&failures.count); failed != 0 {
+		fmt.Printf("%d of %d messages could not be uploaded\n", failed, n)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%d messages uploaded\n", n)
+}
+
 // parseJSON parses a JSON formatted string into a map.
 func parseJSON(v string) map[string]interface{} {
 	var m map[string]interface{}
@@ -94,3 +131,17 @@ func (c callback) Failure(m analytics.Message, err error) {
 func (c callback) Success(_ analytics.Message) {
 	c <- nil
 }
+
+// failureCounter implements the analytics.Callback interface. It is used by the
+// CLI to count the messages that failed to be uploaded when replaying dead
+// letters.
+type failureCounter struct {
+	count int64
+}
+
+func (c *failureCounter) Failure(m analytics.Message, err error) {
+	fmt.Printf("could not upload message %v due to %v\n", m, err)
+	atomic.AddInt64(&c.count, 1)
+}
+
+func (c *failureCounter) Success(_ analytics.Message) {}
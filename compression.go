@@ -0,0 +1,50 @@
+package analytics
+
+import (
+	"bytes"
+	"compress/gzip"
+)
+
+// Values of this type are used to configure how clients compress the body of
+// the batch requests they send to the API.
+type Compression int
+
+const (
+	// Batch requests are sent uncompressed, this is the default.
+	CompressionNone Compression = iota
+
+	// Batch requests are compressed with gzip and sent with a
+	// `Content-Encoding: gzip` header.
+	CompressionGzip
+)
+
+// Returns the value of the `Content-Encoding` header of requests compressed
+// with the algorithm, or an empty string if requests are not compressed.
+func (c Compression) contentEncoding() string {
+	switch c {
+	case CompressionGzip:
+		return "gzip"
+	}
+	return ""
+}
+
+// Compresses the serialized batch passed as argument, the returned slice is the
+// argument itself when compression is disabled.
+func (c Compression) compress(b []byte) ([]byte, error) {
+	switch c {
+	case CompressionGzip:
+		buf := bytes.NewBuffer(make([]byte, 0, len(b)/4))
+		w := gzip.NewWriter(buf)
+
+		if _, err := w.Write(b); err != nil {
+			return nil, err
+		}
+
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	}
+	return b, nil
+}
@@ -0,0 +1,46 @@
+package analytics
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+)
+
+func TestCompressionNone(t *testing.T) {
+	b := []byte(`{"batch":[]}`)
+
+	if res, err := CompressionNone.compress(b); err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(res, b) {
+		t.Errorf("uncompressed batches should not be modified: %q", res)
+	}
+
+	if enc := CompressionNone.contentEncoding(); enc != "" {
+		t.Error("invalid content encoding for uncompressed batches:", enc)
+	}
+}
+
+func TestCompressionGzip(t *testing.T) {
+	b := []byte(`{"batch":[]}`)
+
+	res, err := CompressionGzip.compress(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(res))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dec, err := ioutil.ReadAll(r); err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(dec, b) {
+		t.Errorf("invalid batch decompressed: %q", dec)
+	}
+
+	if enc := CompressionGzip.contentEncoding(); enc != "gzip" {
+		t.Error("invalid content encoding for gzip batches:", enc)
+	}
+}
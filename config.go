@@ -32,6 +32,8 @@ type Config struct {
 
 	// The logger used by the client to output info or error messages when that
 	// are generated by background operations.
+	// If the logger implements `StructuredLogger` the client logs messages with
+	// typed fields instead of formatted strings.
 	// If none is specified the client uses a standard logger that outputs to
 	// `os.Stderr`.
 	Logger Logger
@@ -47,10 +49,27 @@ type Config struct {
 	// which is independent from the number of embedded messages.
 	BatchSize int
 
+	// The maximum number of messages that can be queued by the client before
+	// they are picked up by the goroutine applying the batching rules, set to
+	// `DefaultQueueSize` by default.
+	QueueSize int
+
+	// The policy applied when a message is queued while the queue is full, by
+	// default queuing a message blocks until there is room in the queue.
+	OverflowPolicy OverflowPolicy
+
+	// How long queuing a message may block when the overflow policy is
+	// `OverflowBlockWithTimeout`, set to `DefaultEnqueueTimeout` by default.
+	EnqueueTimeout time.Duration
+
 	// When set to true the client will send more frequent and detailed messages
-	// to its logger.
+	// to its logger, this is equivalent to setting `LogLevel` to `LevelDebug`.
 	Verbose bool
 
+	// The minimum level of the messages sent by the client to its logger, set
+	// to `LevelInfo` by default.
+	LogLevel LogLevel
+
 	// The default context set on each message sent by the client.
 	DefaultContext *Context
 
@@ -61,6 +80,56 @@ type Config struct {
 	// If not set the client will fallback to use a default retry policy.
 	RetryAfter func(int) time.Duration
 
+	// The function used by the client to decide whether a failed request should
+	// be retried, it is called with the error that caused the failure and
+	// returns true if the request may succeed if it was sent again.
+	// If not set the client uses `DefaultRetryPolicy`.
+	RetryPolicy func(error) bool
+
+	// The maximum number of times the client tries to send a batch before
+	// giving up and reporting its messages as failed, set to
+	// `DefaultMaxAttempts` by default.
+	MaxAttempts int
+
+	// The middleware that messages are passed through when they are queued,
+	// in order, see `Middleware` for details.
+	Middleware []Middleware
+
+	// The compression algorithm applied by the client to the body of batch
+	// requests, batches are sent uncompressed by default.
+	// Note that the API limits on the size of batches and messages still apply
+	// to their uncompressed representation.
+	Compression Compression
+
+	// The storage used by the client to persist messages before they are
+	// queued, so they can be recovered after the application crashed or was
+	// killed.
+	// Messages are acknowledged in the storage once the client is done with
+	// them: when they were successfully sent, when they were reported to the
+	// callback with an error that the client will never retry, or when they
+	// were written to the dead-letter sink. Messages abandoned because the
+	// client was closed are only acknowledged if written to the sink.
+	// Unacknowledged messages found in the storage are sent again when a new
+	// client is created with it.
+	// The client takes ownership of the storage and closes it when the client
+	// itself is closed.
+	// If none is specified messages are only kept in memory.
+	Storage Storage
+
+	// The sink where the client writes batches that it gave up on, either
+	// because all attempts at sending them failed or because the client was
+	// closed before they could be sent. Batches written to the sink can be
+	// submitted again with `ReplayDeadLetters`.
+	// The client takes ownership of the sink and closes it when the client
+	// itself is closed.
+	// If none is specified failed batches are discarded.
+	DeadLetter DeadLetter
+
+	// The tracer notified by the client when it sends batches of messages, this
+	// is useful to instrument uploads with a tracing system like OpenTelemetry.
+	// If none is specified batches are not traced.
+	Tracer Tracer
+
 	// A function called by the client to generate unique message identifiers.
 	// The client uses a UUID generator if none is provided.
 	// This field is not exported and only exposed internally to let unit tests
@@ -92,6 +161,18 @@ const DefaultInterval = 5 * time.Second
 // was explicitly set.
 const DefaultBatchSize = 250
 
+// This constant sets the default queue size used by client instances if none
+// was explicitly set.
+const DefaultQueueSize = 100
+
+// This constant sets the default time that queuing a message may block for
+// when the overflow policy is `OverflowBlockWithTimeout`.
+const DefaultEnqueueTimeout = 100 * time.Millisecond
+
+// This constant sets the default number of attempts made to send a batch if none
+// was explicitly set.
+const DefaultMaxAttempts = 10
+
 // Verifies that fields that don't have zero-values are set to valid values,
 // returns an error describing the problem if a field was invalid.
 func (c *Config) validate() error {
@@ -111,6 +192,46 @@ func (c *Config) validate() error {
 		}
 	}
 
+	if c.QueueSize < 0 {
+		return ConfigError{
+			Reason: "negative queue sizes are not supported",
+			Field:  "QueueSize",
+			Value:  c.QueueSize,
+		}
+	}
+
+	if !c.OverflowPolicy.valid() {
+		return ConfigError{
+			Reason: "unknown overflow policy",
+			Field:  "OverflowPolicy",
+			Value:  c.OverflowPolicy,
+		}
+	}
+
+	if c.EnqueueTimeout < 0 {
+		return ConfigError{
+			Reason: "negative time intervals are not supported",
+			Field:  "EnqueueTimeout",
+			Value:  c.EnqueueTimeout,
+		}
+	}
+
+	if c.MaxAttempts < 0 {
+		return ConfigError{
+			Reason: "negative attempt counts are not supported",
+			Field:  "MaxAttempts",
+			Value:  c.MaxAttempts,
+		}
+	}
+
+	if c.Compression != CompressionNone && c.Compression != CompressionGzip {
+		return ConfigError{
+			Reason: "unknown compression algorithm",
+			Field:  "Compression",
+			Value:  c.Compression,
+		}
+	}
+
 	return nil
 }
 
@@ -133,10 +254,22 @@ func makeConfig(c Config) Config {
 		c.Logger = newDefaultLogger()
 	}
 
+	if c.Verbose && c.LogLevel > LevelDebug {
+		c.LogLevel = LevelDebug
+	}
+
 	if c.BatchSize == 0 {
 		c.BatchSize = DefaultBatchSize
 	}
 
+	if c.QueueSize == 0 {
+		c.QueueSize = DefaultQueueSize
+	}
+
+	if c.EnqueueTimeout == 0 {
+		c.EnqueueTimeout = DefaultEnqueueTimeout
+	}
+
 	if c.DefaultContext == nil {
 		c.DefaultContext = &Context{}
 	}
@@ -145,6 +278,18 @@ func makeConfig(c Config) Config {
 		c.RetryAfter = backo.DefaultBacko().Duration
 	}
 
+	if c.RetryPolicy == nil {
+		c.RetryPolicy = DefaultRetryPolicy
+	}
+
+	if c.Tracer == nil {
+		c.Tracer = nopTracer{}
+	}
+
+	if c.MaxAttempts == 0 {
+		c.MaxAttempts = DefaultMaxAttempts
+	}
+
 	if c.uid == nil {
 		c.uid = uid
 	}
@@ -44,3 +44,67 @@ func TestConfigInvalidBatchSize(t *testing.T) {
 		t.Error("invalid field error reported:", e)
 	}
 }
+
+func TestConfigInvalidCompression(t *testing.T) {
+	c := Config{
+		Compression: Compression(42),
+	}
+
+	if err := c.validate(); err == nil {
+		t.Error("no error returned when validating a malformed config")
+
+	} else if e, ok := err.(ConfigError); !ok {
+		t.Error("invalid error returned when checking a malformed config:", err)
+
+	} else if e.Field != "Compression" || e.Value.(Compression) != 42 {
+		t.Error("invalid field error reported:", e)
+	}
+}
+
+func TestConfigInvalidMaxAttempts(t *testing.T) {
+	c := Config{
+		MaxAttempts: -1,
+	}
+
+	if err := c.validate(); err == nil {
+		t.Error("no error returned when validating a malformed config")
+
+	} else if e, ok := err.(ConfigError); !ok {
+		t.Error("invalid error returned when checking a malformed config:", err)
+
+	} else if e.Field != "MaxAttempts" || e.Value.(int) != -1 {
+		t.Error("invalid field error reported:", e)
+	}
+}
+
+func TestConfigInvalidQueueSize(t *testing.T) {
+	c := Config{
+		QueueSize: -1,
+	}
+
+	if err := c.validate(); err == nil {
+		t.Error("no error returned when validating a malformed config")
+
+	} else if e, ok := err.(ConfigError); !ok {
+		t.Error("invalid error returned when checking a malformed config:", err)
+
+	} else if e.Field != "QueueSize" || e.Value.(int) != -1 {
+		t.Error("invalid field error reported:", e)
+	}
+}
+
+func TestConfigInvalidOverflowPolicy(t *testing.T) {
+	c := Config{
+		OverflowPolicy: OverflowPolicy(42),
+	}
+
+	if err := c.validate(); err == nil {
+		t.Error("no error returned when validating a malformed config")
+
+	} else if e, ok := err.(ConfigError); !ok {
+		t.Error("invalid error returned when checking a malformed config:", err)
+
+	} else if e.Field != "OverflowPolicy" || e.Value.(OverflowPolicy) != 42 {
+		t.Error("invalid field error reported:", e)
+	}
+}
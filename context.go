@@ -148,3 +148,103 @@ func (ctx Context) MarshalJSON() ([]byte, error) {
 
 	return json.Marshal(structToMap(v, m))
 }
+
+// MergeContext returns a copy of the message passed as first argument with ctx
+// merged into its context. Fields already set on the message context take
+// precedence over the ones of ctx, nested objects are merged field by field
+// and the keys of the `Traits` and `Extra` maps are merged the same way.
+//
+// The message is returned unchanged if ctx is nil or if it is not one of the
+// message types of this package. The context of the message and ctx are not
+// modified.
+func MergeContext(msg Message, ctx *Context) Message {
+	if ctx == nil {
+		return msg
+	}
+
+	switch m := dereferenceMessage(msg).(type) {
+	case Alias:
+		m.Context = mergeContext(m.Context, ctx)
+		return m
+	case Group:
+		m.Context = mergeContext(m.Context, ctx)
+		return m
+	case Identify:
+		m.Context = mergeContext(m.Context, ctx)
+		return m
+	case Page:
+		m.Context = mergeContext(m.Context, ctx)
+		return m
+	case Screen:
+		m.Context = mergeContext(m.Context, ctx)
+		return m
+	case Track:
+		m.Context = mergeContext(m.Context, ctx)
+		return m
+	}
+
+	return msg
+}
+
+// Returns a new context with the zero-value fields of dst set to the values of
+// the same fields in src.
+func mergeContext(dst *Context, src *Context) *Context {
+	var c Context
+
+	if dst != nil {
+		c = *dst
+	}
+
+	if src != nil {
+		mergeValue(reflect.ValueOf(&c).Elem(), reflect.ValueOf(*src))
+	}
+
+	return &c
+}
+
+func mergeValue(dst reflect.Value, src reflect.Value) {
+	switch dst.Kind() {
+	case reflect.Struct:
+		for i, n := 0, dst.NumField(); i != n; i++ {
+			mergeValue(dst.Field(i), src.Field(i))
+		}
+
+	case reflect.Map:
+		if dst.Len() == 0 && src.Len() == 0 {
+			return
+		}
+
+		// The maps are always merged into a new one so the merged context
+		// doesn't share them with the contexts it was built from, keys of dst
+		// are copied last so they take precedence.
+		m := reflect.MakeMapWithSize(dst.Type(), dst.Len()+src.Len())
+
+		for _, k := range src.MapKeys() {
+			m.SetMapIndex(k, src.MapIndex(k))
+		}
+
+		for _, k := range dst.MapKeys() {
+			m.SetMapIndex(k, dst.MapIndex(k))
+		}
+
+		dst.Set(m)
+
+	case reflect.Slice:
+		// Slices are copied for the same reason than maps.
+		v := dst
+		if v.Len() == 0 {
+			v = src
+		}
+
+		if v.Len() != 0 {
+			c := reflect.MakeSlice(dst.Type(), v.Len(), v.Len())
+			reflect.Copy(c, v)
+			dst.Set(c)
+		}
+
+	default:
+		if isZeroValue(dst) {
+			dst.Set(src)
+		}
+	}
+}
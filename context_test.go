@@ -2,6 +2,8 @@ package analytics
 
 import (
 	"encoding/json"
+	"net"
+	"reflect"
 	"testing"
 )
 
@@ -34,3 +36,61 @@ func TestContextMarshalJSONExtra(t *testing.T) {
 		t.Error("invalid marshaled representation of context:", s)
 	}
 }
+
+func TestMergeContext(t *testing.T) {
+	ctx := &Context{
+		IP:        net.ParseIP("10.0.0.1"),
+		UserAgent: "test",
+		Locale:    "fr-FR",
+		Page:      PageInfo{Path: "/", URL: "http://example.com/"},
+		Traits:    Traits{"email": "a@example.com", "name": "A"},
+	}
+
+	msg := &Track{
+		UserId: "1",
+		Event:  "A",
+		Context: &Context{
+			Locale: "en-US",
+			Page:   PageInfo{Title: "Home", Path: "/home"},
+			Traits: Traits{"name": "B"},
+		},
+	}
+
+	merged := MergeContext(msg, ctx)
+
+	if !reflect.DeepEqual(merged, Track{
+		UserId: "1",
+		Event:  "A",
+		Context: &Context{
+			IP:        net.ParseIP("10.0.0.1"),
+			UserAgent: "test",
+			Locale:    "en-US",
+			Page:      PageInfo{Title: "Home", Path: "/home", URL: "http://example.com/"},
+			Traits:    Traits{"email": "a@example.com", "name": "B"},
+		},
+	}) {
+		t.Errorf("invalid merged message: %#v", merged)
+	}
+
+	if !reflect.DeepEqual(msg.Context.Traits, Traits{"name": "B"}) {
+		t.Error("the context of the message was modified:", msg.Context.Traits)
+	}
+
+	if !reflect.DeepEqual(ctx.Traits, Traits{"email": "a@example.com", "name": "A"}) {
+		t.Error("the merged context was modified:", ctx.Traits)
+	}
+}
+
+func TestMergeContextNilContext(t *testing.T) {
+	ctx := &Context{UserAgent: "test"}
+
+	if msg := MergeContext(Identify{UserId: "1"}, ctx); !reflect.DeepEqual(msg, Identify{UserId: "1", Context: ctx}) {
+		t.Errorf("invalid merged message: %#v", msg)
+	} else if msg.(Identify).Context == ctx {
+		t.Error("the merged context was not copied")
+	}
+
+	if msg := MergeContext(Identify{UserId: "1"}, nil); !reflect.DeepEqual(msg, Identify{UserId: "1"}) {
+		t.Errorf("invalid message: %#v", msg)
+	}
+}
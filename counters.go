@@ -0,0 +1,157 @@
+package analytics
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// This type holds the counters maintained by a client about the messages it
+// processed, the fields are updated atomically and may be read concurrently.
+type counters struct {
+	enqueued  int64
+	dropped   int64
+	delivered int64
+	failed    int64
+	abandoned int64
+	retries   int64
+	rejected  int64
+
+	// Gauges of the number of batches being sent and of the number of messages
+	// waiting in the batch loop's message queue.
+	inFlight int64
+	pending  int64
+
+	batchMessages *histogram
+	batchBytes    *histogram
+	uploadLatency *histogram
+	messageBytes  *histogram
+
+	// Number of responses received from the API, indexed by status code.
+	mutex       sync.Mutex
+	statusCodes map[int]int64
+}
+
+func newCounters() *counters {
+	return &counters{
+		batchMessages: newHistogram(1, 5, 10, 25, 50, 100, 250, 500, 1000),
+		batchBytes:    newHistogram(1e3, 5e3, 10e3, 50e3, 100e3, 250e3, 500e3),
+		uploadLatency: newHistogram(.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10),
+		messageBytes:  newHistogram(100, 250, 500, 1e3, 2.5e3, 5e3, 10e3, 32e3),
+		statusCodes:   make(map[int]int64),
+	}
+}
+
+func (c *counters) add(counter *int64, n int) {
+	atomic.AddInt64(counter, int64(n))
+}
+
+func (c *counters) set(gauge *int64, n int) {
+	atomic.StoreInt64(gauge, int64(n))
+}
+
+func (c *counters) addStatusCode(code int) {
+	c.mutex.Lock()
+	c.statusCodes[code]++
+	c.mutex.Unlock()
+}
+
+func (c *counters) snapshot() CloseError {
+	return CloseError{
+		Delivered: int(atomic.LoadInt64(&c.delivered)),
+		Failed:    int(atomic.LoadInt64(&c.failed)),
+		Abandoned: int(atomic.LoadInt64(&c.abandoned)),
+	}
+}
+
+// Returns the difference between the current value of the counters and the
+// snapshot passed as argument.
+func (c *counters) since(start CloseError) CloseError {
+	now := c.snapshot()
+	return CloseError{
+		Delivered: now.Delivered - start.Delivered,
+		Failed:    now.Failed - start.Failed,
+		Abandoned: now.Abandoned - start.Abandoned,
+	}
+}
+
+// Returns the statistics exposed by the counters, queued is the number of
+// messages waiting to be picked up by the batch loop.
+func (c *counters) stats(queued int) Stats {
+	c.mutex.Lock()
+	statusCodes := make(map[int]int64, len(c.statusCodes))
+	for code, n := range c.statusCodes {
+		statusCodes[code] = n
+	}
+	c.mutex.Unlock()
+
+	return Stats{
+		Enqueued:      atomic.LoadInt64(&c.enqueued),
+		Dropped:       atomic.LoadInt64(&c.dropped),
+		Delivered:     atomic.LoadInt64(&c.delivered),
+		Failed:        atomic.LoadInt64(&c.failed),
+		Abandoned:     atomic.LoadInt64(&c.abandoned),
+		Retries:       atomic.LoadInt64(&c.retries),
+		Rejected:      atomic.LoadInt64(&c.rejected),
+		QueueDepth:    int64(queued) + atomic.LoadInt64(&c.pending),
+		InFlight:      atomic.LoadInt64(&c.inFlight),
+		BatchMessages: c.batchMessages.snapshot(),
+		BatchBytes:    c.batchBytes.snapshot(),
+		UploadLatency: c.uploadLatency.snapshot(),
+		MessageBytes:  c.messageBytes.snapshot(),
+		StatusCodes:   statusCodes,
+	}
+}
+
+// This type is a histogram with fixed buckets, it is safe to use concurrently.
+type histogram struct {
+	mutex   sync.Mutex
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+// Creates a histogram with buckets delimited by the upper bounds passed as
+// arguments, in increasing order.
+func newHistogram(buckets ...float64) *histogram {
+	return &histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (h *histogram) observe(v float64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+			break
+		}
+	}
+
+	h.count++
+	h.sum += v
+}
+
+func (h *histogram) snapshot() Histogram {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	s := Histogram{
+		Buckets: h.buckets,
+		Counts:  make([]uint64, len(h.counts)),
+		Count:   h.count,
+		Sum:     h.sum,
+	}
+
+	var n uint64
+
+	for i, c := range h.counts {
+		n += c
+		s.Counts[i] = n
+	}
+
+	return s
+}
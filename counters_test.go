@@ -0,0 +1,61 @@
+package analytics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCountersSince(t *testing.T) {
+	c := &counters{}
+	c.add(&c.delivered, 2)
+
+	start := c.snapshot()
+	c.add(&c.delivered, 3)
+	c.add(&c.failed, 1)
+	c.add(&c.abandoned, 4)
+
+	if e := c.since(start); e != (CloseError{Delivered: 3, Failed: 1, Abandoned: 4}) {
+		t.Errorf("invalid counters returned: %#v", e)
+	}
+}
+
+func TestHistogramSnapshot(t *testing.T) {
+	h := newHistogram(1, 10, 100)
+
+	for _, v := range []float64{0.5, 1, 5, 50, 500} {
+		h.observe(v)
+	}
+
+	s := h.snapshot()
+
+	if !reflect.DeepEqual(s.Counts, []uint64{2, 3, 4}) {
+		t.Error("invalid cumulative bucket counts:", s.Counts)
+	}
+
+	if s.Count != 5 || s.Sum != 556.5 {
+		t.Errorf("invalid histogram count or sum: %d, %g", s.Count, s.Sum)
+	}
+}
+
+func TestCountersStats(t *testing.T) {
+	c := newCounters()
+	c.add(&c.enqueued, 10)
+	c.add(&c.dropped, 1)
+	c.add(&c.delivered, 7)
+	c.set(&c.pending, 2)
+	c.batchMessages.observe(7)
+
+	s := c.stats(3)
+
+	if s.Enqueued != 10 || s.Dropped != 1 || s.Delivered != 7 {
+		t.Errorf("invalid stats counters: %#v", s)
+	}
+
+	if s.QueueDepth != 5 {
+		t.Error("the queue depth must include queued and pending messages:", s.QueueDepth)
+	}
+
+	if s.BatchMessages.Count != 1 || s.BatchMessages.Sum != 7 {
+		t.Errorf("invalid batch messages histogram: %#v", s.BatchMessages)
+	}
+}
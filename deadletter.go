@@ -0,0 +1,227 @@
+package analytics
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Values implementing this interface are used by analytics clients to keep the
+// batches that they failed to send, so they can be submitted again later with
+// `ReplayDeadLetters`.
+//
+// DeadLetter methods may be called concurrently from multiple goroutines.
+type DeadLetter interface {
+
+	// Persists the failed batch passed as argument.
+	Write(batch DeadLetterBatch) error
+
+	// Releases the resources held by the dead-letter sink.
+	Close() error
+}
+
+// This type represents a batch that a client failed to send.
+type DeadLetterBatch struct {
+
+	// The time at which the client gave up on sending the batch.
+	Time time.Time `json:"time"`
+
+	// The error returned by the last attempt at sending the batch.
+	Error string `json:"error"`
+
+	// The number of attempts made at sending the batch.
+	Attempts int `json:"attempts"`
+
+	// The JSON representation of the batch, as it would have been sent to the
+	// API.
+	Batch json.RawMessage `json:"batch"`
+}
+
+// Opens a dead-letter sink that appends failed batches to JSONL files in the
+// directory passed as argument, the directory is created if it didn't exist.
+//
+// Batches are written to one file per day, named after the UTC date at which
+// they were written (for example 2009-11-10.jsonl).
+func NewDeadLetterDir(dir string) (DeadLetter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &deadLetterDir{dir: dir}, nil
+}
+
+const deadLetterExt = ".jsonl"
+
+type deadLetterDir struct {
+	dir    string
+	mutex  sync.Mutex
+	closed bool
+}
+
+func (d *deadLetterDir) Write(batch DeadLetterBatch) error {
+	b, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.closed {
+		return errDeadLetterClosed
+	}
+
+	name := batch.Time.UTC().Format("2006-01-02") + deadLetterExt
+
+	f, err := os.OpenFile(filepath.Join(d.dir, name), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func (d *deadLetterDir) Close() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.closed {
+		return errDeadLetterClosed
+	}
+
+	d.closed = true
+	return nil
+}
+
+// This interface is implemented by clients which can queue messages that were
+// already serialized, like the clients created by this package.
+type rawEnqueuer interface {
+	enqueueRaw(ctx context.Context, msg Message, b []byte) error
+}
+
+// This error is returned by dead-letter methods called after the sink was
+// closed.
+var errDeadLetterClosed = errors.New("the dead-letter sink was already closed")
+
+// Submits again the messages of the failed batches found at path, which is
+// either a JSONL file written by a dead-letter sink or a directory of such
+// files.
+//
+// Messages are queued on the client passed as argument exactly as they were
+// written to the dead-letter sink, they don't go through the middleware of the
+// client and keep their original message id and timestamp, so the API can
+// deduplicate messages that were submitted more than once. They are then sent
+// like any other message, with the retry policy, compression and storage of
+// the client. The client must have been created by this package.
+//
+// The function returns the number of messages that were queued, it stops at
+// the first error. Files are left untouched, it is up to the caller to remove
+// them once the client was closed and all messages were delivered.
+func ReplayDeadLetters(ctx context.Context, client Client, path string) (n int, err error) {
+	var files []string
+
+	raw, ok := client.(rawEnqueuer)
+	if !ok {
+		err = fmt.Errorf("analytics.ReplayDeadLetters: unsupported client type: %T", client)
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+
+	if !info.IsDir() {
+		files = []string{path}
+	} else {
+		var entries []os.FileInfo
+
+		if entries, err = ioutil.ReadDir(path); err != nil {
+			return
+		}
+
+		for _, e := range entries {
+			if !e.IsDir() && strings.HasSuffix(e.Name(), deadLetterExt) {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+
+		sort.Strings(files)
+	}
+
+	for _, file := range files {
+		var c int
+		c, err = replayDeadLetterFile(ctx, raw, file)
+		n += c
+
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func replayDeadLetterFile(ctx context.Context, client rawEnqueuer, path string) (n int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	r := bufio.NewScanner(f)
+	r.Buffer(nil, 2*maxBatchBytes)
+
+	for line := 1; r.Scan(); line++ {
+		var dead DeadLetterBatch
+		var batch struct {
+			Messages []json.RawMessage `json:"batch"`
+		}
+
+		if len(r.Bytes()) == 0 {
+			continue
+		}
+
+		if err = json.Unmarshal(r.Bytes(), &dead); err == nil {
+			err = json.Unmarshal(dead.Batch, &batch)
+		}
+
+		if err != nil {
+			err = fmt.Errorf("analytics.ReplayDeadLetters: %s:%d: %s", path, line, err)
+			return
+		}
+
+		for _, b := range batch.Messages {
+			var msg Message
+
+			// The decoded message is only reported to the callback, the raw
+			// JSON is what gets sent so no fields are lost.
+			if msg, err = decodeMessage(b); err != nil {
+				err = fmt.Errorf("analytics.ReplayDeadLetters: %s:%d: %s", path, line, err)
+				return
+			}
+
+			if err = client.enqueueRaw(ctx, msg, b); err != nil {
+				return
+			}
+
+			n++
+		}
+	}
+
+	err = r.Err()
+	return
+}
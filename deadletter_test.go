@@ -0,0 +1,220 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeadLetterDir(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	d, err := NewDeadLetterDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, batch := range []DeadLetterBatch{
+		{Time: mockTime(), Error: "A", Attempts: 1, Batch: json.RawMessage(`{"batch":[]}`)},
+		{Time: mockTime().Add(-time.Hour), Error: "B", Attempts: 2, Batch: json.RawMessage(`{"batch":[]}`)},
+	} {
+		if err := d.Write(batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Write(DeadLetterBatch{}); err != errDeadLetterClosed {
+		t.Error("writing to a closed dead-letter sink should fail:", err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "2009-11-10.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const ref = `{"time":"2009-11-10T23:00:00Z","error":"A","attempts":1,"batch":{"batch":[]}}
+{"time":"2009-11-10T22:00:00Z","error":"B","attempts":2,"batch":{"batch":[]}}
+`
+
+	if string(b) != ref {
+		t.Errorf("invalid dead-letter file:\n- expected %s\n- found: %s", ref, b)
+	}
+}
+
+func TestClientDeadLetter(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	deadLetter, err := NewDeadLetterDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errchan := make(chan error, 1)
+
+	client, _ := NewWithConfig("0123456789", Config{
+		Logger: testLogger{t.Logf, t.Logf},
+		Callback: testCallback{
+			nil,
+			func(m Message, e error) { errchan <- e },
+		},
+		Transport:   testTransportError,
+		BatchSize:   1,
+		MaxAttempts: 2,
+		RetryAfter:  func(i int) time.Duration { return time.Millisecond },
+		DeadLetter:  deadLetter,
+		now:         mockTime,
+		uid:         mockId,
+	})
+
+	client.Enqueue(Track{UserId: "123456", Event: "Download"})
+	<-errchan
+	client.Close()
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "2009-11-10.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var batch DeadLetterBatch
+
+	if err := json.Unmarshal(b, &batch); err != nil {
+		t.Fatal(err)
+	}
+
+	if batch.Attempts != 2 || !strings.HasSuffix(batch.Error, testError.Error()) {
+		t.Errorf("invalid dead-letter batch: %d attempts, %q", batch.Attempts, batch.Error)
+	}
+
+	// The batch must be submitted again with its original message id and
+	// timestamp.
+	successes := make(chan Message, 1)
+
+	client, _ = NewWithConfig("0123456789", Config{
+		Logger: testLogger{t.Logf, t.Logf},
+		Callback: testCallback{
+			func(m Message) { successes <- m },
+			nil,
+		},
+		Transport: testTransportOK,
+		BatchSize: 1,
+	})
+	defer client.Close()
+
+	if n, err := ReplayDeadLetters(context.Background(), client, dir); err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Error("invalid number of messages replayed:", n)
+	}
+
+	ref := Track{
+		Type:      "track",
+		MessageId: mockId(),
+		UserId:    "123456",
+		Event:     "Download",
+		Timestamp: mockTime(),
+	}
+
+	if msg := <-successes; !reflect.DeepEqual(msg, ref) {
+		t.Errorf("invalid message replayed:\n- expected %#v\n- found: %#v", ref, msg)
+	}
+}
+
+func TestReplayDeadLettersRaw(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "2009-11-10.jsonl")
+	line := `{"time":"2009-11-10T23:00:00Z","error":"test error","attempts":2,"batch":{"batch":[` +
+		`{"type":"track","messageId":"A","userId":"1","event":"Order Completed","timestamp":"2009-11-10T23:00:00Z",` +
+		`"properties":{"revenue":42},"context":{"custom":{"source":"billing"}}}]}}` + "\n"
+
+	if err := ioutil.WriteFile(path, []byte(line), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	body, server := mockServer()
+	defer server.Close()
+
+	client, _ := NewWithConfig("0123456789", Config{
+		Endpoint:  server.URL,
+		Logger:    testLogger{t.Logf, t.Logf},
+		BatchSize: 1,
+		// Replayed messages were already processed by the middleware before
+		// they were dead-lettered, they must not be dropped or modified again.
+		Middleware: []Middleware{
+			func(msg Message) (Message, error) { return nil, nil },
+		},
+	})
+	defer client.Close()
+
+	if n, err := ReplayDeadLetters(context.Background(), client, path); err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Error("invalid number of messages replayed:", n)
+	}
+
+	var batch struct {
+		Messages []map[string]interface{} `json:"batch"`
+	}
+
+	if err := json.Unmarshal(<-body, &batch); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(batch.Messages) != 1 {
+		t.Fatal("invalid number of messages sent:", len(batch.Messages))
+	}
+
+	ref := map[string]interface{}{
+		"type":       "track",
+		"messageId":  "A",
+		"userId":     "1",
+		"event":      "Order Completed",
+		"timestamp":  "2009-11-10T23:00:00Z",
+		"properties": map[string]interface{}{"revenue": 42.0},
+		"context":    map[string]interface{}{"custom": map[string]interface{}{"source": "billing"}},
+	}
+
+	if msg := batch.Messages[0]; !reflect.DeepEqual(msg, ref) {
+		t.Errorf("invalid message replayed:\n- expected %#v\n- found: %#v", ref, msg)
+	}
+}
+
+func TestReplayDeadLettersUnsupportedClient(t *testing.T) {
+	if _, err := ReplayDeadLetters(context.Background(), struct{ Client }{}, "."); err == nil {
+		t.Error("no error returned when replaying dead letters with an unsupported client")
+	}
+}
+
+func TestReplayDeadLettersInvalid(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "2009-11-10.jsonl")
+
+	if err := ioutil.WriteFile(path, []byte("{}\nnot json\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client, _ := NewWithConfig("0123456789", Config{
+		Logger:    testLogger{t.Logf, t.Logf},
+		Transport: testTransportOK,
+	})
+	defer client.Close()
+
+	if _, err := ReplayDeadLetters(context.Background(), client, path); err == nil {
+		t.Error("no error returned when replaying an invalid dead-letter file")
+	}
+}
@@ -0,0 +1,377 @@
+package analytics
+
+import "fmt"
+
+// This file defines the events of the e-commerce spec as described in
+// https://segment.com/docs/connections/spec/ecommerce/v2/
+
+// This type represents the properties of the Products Searched event.
+type ProductsSearched struct {
+	Query string
+}
+
+// This type represents the properties of the Product List Viewed event.
+type ProductListViewed struct {
+	ListId   string
+	Category string
+	Products []Product
+}
+
+// This type represents the properties of the Product Clicked event.
+type ProductClicked struct {
+	Product Product
+}
+
+// This type represents the properties of the Product Viewed event.
+type ProductViewed struct {
+	Product  Product
+	Currency string
+	Value    float64
+}
+
+// This type represents the properties of the Product Added event.
+type ProductAdded struct {
+	CartId  string
+	Product Product
+}
+
+// This type represents the properties of the Product Removed event.
+type ProductRemoved struct {
+	CartId  string
+	Product Product
+}
+
+// This type represents the properties of the Cart Viewed event.
+type CartViewed struct {
+	CartId   string
+	Products []Product
+}
+
+// This type represents the properties shared by the checkout and order events.
+type Order struct {
+	OrderId     string
+	CheckoutId  string
+	Affiliation string
+	Total       float64
+	Subtotal    float64
+	Revenue     float64
+	Shipping    float64
+	Tax         float64
+	Discount    float64
+	Coupon      string
+	Currency    string
+	Products    []Product
+}
+
+// This type represents the properties of the Checkout Started event, unlike
+// the order events the order id is not required since the order may not exist
+// yet.
+type CheckoutStarted Order
+
+// This type represents the properties of the Order Completed event.
+type OrderCompleted Order
+
+// This type represents the properties of the Order Updated event.
+type OrderUpdated Order
+
+// This type represents the properties of the Order Refunded event.
+type OrderRefunded Order
+
+// This type represents the properties of the Order Cancelled event.
+type OrderCancelled Order
+
+// This type represents the properties of the checkout step events.
+type CheckoutStep struct {
+	CheckoutId     string
+	OrderId        string
+	Step           int
+	ShippingMethod string
+	PaymentMethod  string
+}
+
+// This type represents the properties of the Checkout Step Viewed event.
+type CheckoutStepViewed CheckoutStep
+
+// This type represents the properties of the Checkout Step Completed event.
+type CheckoutStepCompleted CheckoutStep
+
+// This type represents the properties of the Payment Info Entered event.
+type PaymentInfoEntered CheckoutStep
+
+// This type represents the properties of the coupon events.
+type Coupon struct {
+	OrderId    string
+	CartId     string
+	CouponId   string
+	CouponName string
+	Discount   float64
+
+	// The reason why the coupon was denied, only used by the Coupon Denied
+	// event.
+	Reason string
+}
+
+// This type represents the properties of the Coupon Entered event.
+type CouponEntered Coupon
+
+// This type represents the properties of the Coupon Applied event.
+type CouponApplied Coupon
+
+// This type represents the properties of the Coupon Denied event.
+type CouponDenied Coupon
+
+// This type represents the properties of the Coupon Removed event.
+type CouponRemoved Coupon
+
+func (e ProductsSearched) Validate() error { return nil }
+
+func (e ProductsSearched) Apply(msg *Track) {
+	msg.Event = "Products Searched"
+	msg.Properties.setString("query", e.Query)
+}
+
+func (e ProductListViewed) Validate() error {
+	return validateProducts("analytics.ProductListViewed", e.Products)
+}
+
+func (e ProductListViewed) Apply(msg *Track) {
+	msg.Event = "Product List Viewed"
+	msg.Properties.
+		setString("list_id", e.ListId).
+		setString("category", e.Category).
+		setProducts(e.Products)
+}
+
+func (e ProductClicked) Validate() error {
+	return validateProduct("analytics.ProductClicked", "Product", e.Product)
+}
+
+func (e ProductClicked) Apply(msg *Track) {
+	msg.Event = "Product Clicked"
+	msg.Properties.setProduct(e.Product)
+}
+
+func (e ProductViewed) Validate() error {
+	return validateProduct("analytics.ProductViewed", "Product", e.Product)
+}
+
+func (e ProductViewed) Apply(msg *Track) {
+	msg.Event = "Product Viewed"
+	msg.Properties.
+		setProduct(e.Product).
+		setString("currency", e.Currency).
+		setFloat("value", e.Value)
+}
+
+func (e ProductAdded) Validate() error {
+	return validateProduct("analytics.ProductAdded", "Product", e.Product)
+}
+
+func (e ProductAdded) Apply(msg *Track) {
+	msg.Event = "Product Added"
+	msg.Properties.
+		setString("cart_id", e.CartId).
+		setProduct(e.Product)
+}
+
+func (e ProductRemoved) Validate() error {
+	return validateProduct("analytics.ProductRemoved", "Product", e.Product)
+}
+
+func (e ProductRemoved) Apply(msg *Track) {
+	msg.Event = "Product Removed"
+	msg.Properties.
+		setString("cart_id", e.CartId).
+		setProduct(e.Product)
+}
+
+func (e CartViewed) Validate() error {
+	return validateProducts("analytics.CartViewed", e.Products)
+}
+
+func (e CartViewed) Apply(msg *Track) {
+	msg.Event = "Cart Viewed"
+	msg.Properties.
+		setString("cart_id", e.CartId).
+		setProducts(e.Products)
+}
+
+func (e CheckoutStarted) Validate() error {
+	return validateProducts("analytics.CheckoutStarted", e.Products)
+}
+
+func (e CheckoutStarted) Apply(msg *Track) {
+	msg.Event = "Checkout Started"
+	msg.Properties.setOrder(Order(e))
+}
+
+func (e OrderCompleted) Validate() error {
+	return validateOrder("analytics.OrderCompleted", Order(e))
+}
+
+func (e OrderCompleted) Apply(msg *Track) {
+	msg.Event = "Order Completed"
+	msg.Properties.setOrder(Order(e))
+}
+
+func (e OrderUpdated) Validate() error {
+	return validateOrder("analytics.OrderUpdated", Order(e))
+}
+
+func (e OrderUpdated) Apply(msg *Track) {
+	msg.Event = "Order Updated"
+	msg.Properties.setOrder(Order(e))
+}
+
+func (e OrderRefunded) Validate() error {
+	return validateOrder("analytics.OrderRefunded", Order(e))
+}
+
+func (e OrderRefunded) Apply(msg *Track) {
+	msg.Event = "Order Refunded"
+	msg.Properties.setOrder(Order(e))
+}
+
+func (e OrderCancelled) Validate() error {
+	return validateOrder("analytics.OrderCancelled", Order(e))
+}
+
+func (e OrderCancelled) Apply(msg *Track) {
+	msg.Event = "Order Cancelled"
+	msg.Properties.setOrder(Order(e))
+}
+
+func (e CheckoutStepViewed) Validate() error { return nil }
+
+func (e CheckoutStepViewed) Apply(msg *Track) {
+	msg.Event = "Checkout Step Viewed"
+	msg.Properties.setCheckoutStep(CheckoutStep(e))
+}
+
+func (e CheckoutStepCompleted) Validate() error { return nil }
+
+func (e CheckoutStepCompleted) Apply(msg *Track) {
+	msg.Event = "Checkout Step Completed"
+	msg.Properties.setCheckoutStep(CheckoutStep(e))
+}
+
+func (e PaymentInfoEntered) Validate() error { return nil }
+
+func (e PaymentInfoEntered) Apply(msg *Track) {
+	msg.Event = "Payment Info Entered"
+	msg.Properties.setCheckoutStep(CheckoutStep(e))
+}
+
+func (e CouponEntered) Validate() error { return nil }
+
+func (e CouponEntered) Apply(msg *Track) {
+	msg.Event = "Coupon Entered"
+	msg.Properties.setCoupon(Coupon(e))
+}
+
+func (e CouponApplied) Validate() error { return nil }
+
+func (e CouponApplied) Apply(msg *Track) {
+	msg.Event = "Coupon Applied"
+	msg.Properties.setCoupon(Coupon(e))
+}
+
+func (e CouponDenied) Validate() error { return nil }
+
+func (e CouponDenied) Apply(msg *Track) {
+	msg.Event = "Coupon Denied"
+	msg.Properties.setCoupon(Coupon(e))
+}
+
+func (e CouponRemoved) Validate() error { return nil }
+
+func (e CouponRemoved) Apply(msg *Track) {
+	msg.Event = "Coupon Removed"
+	msg.Properties.setCoupon(Coupon(e))
+}
+
+func validateProduct(typ string, name string, product Product) error {
+	if len(product.ID) == 0 {
+		return FieldError{
+			Type:  typ,
+			Name:  name + ".ID",
+			Value: product.ID,
+		}
+	}
+	return nil
+}
+
+func validateProducts(typ string, products []Product) error {
+	for i, p := range products {
+		if err := validateProduct(typ, fmt.Sprintf("Products[%d]", i), p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateOrder(typ string, order Order) error {
+	if len(order.OrderId) == 0 {
+		return FieldError{
+			Type:  typ,
+			Name:  "OrderId",
+			Value: order.OrderId,
+		}
+	}
+	return validateProducts(typ, order.Products)
+}
+
+func (p Properties) setProduct(product Product) Properties {
+	for k, v := range product.specProperties() {
+		p[k] = v
+	}
+	return p
+}
+
+func (p Properties) setProducts(products []Product) Properties {
+	if len(products) != 0 {
+		list := make([]Properties, len(products))
+
+		for i, product := range products {
+			list[i] = product.specProperties()
+		}
+
+		p["products"] = list
+	}
+	return p
+}
+
+func (p Properties) setOrder(order Order) Properties {
+	return p.
+		setString("order_id", order.OrderId).
+		setString("checkout_id", order.CheckoutId).
+		setString("affiliation", order.Affiliation).
+		setFloat("total", order.Total).
+		setFloat("subtotal", order.Subtotal).
+		setFloat("revenue", order.Revenue).
+		setFloat("shipping", order.Shipping).
+		setFloat("tax", order.Tax).
+		setFloat("discount", order.Discount).
+		setString("coupon", order.Coupon).
+		setString("currency", order.Currency).
+		setProducts(order.Products)
+}
+
+func (p Properties) setCheckoutStep(step CheckoutStep) Properties {
+	return p.
+		setString("checkout_id", step.CheckoutId).
+		setString("order_id", step.OrderId).
+		setInt("step", step.Step).
+		setString("shipping_method", step.ShippingMethod).
+		setString("payment_method", step.PaymentMethod)
+}
+
+func (p Properties) setCoupon(coupon Coupon) Properties {
+	return p.
+		setString("order_id", coupon.OrderId).
+		setString("cart_id", coupon.CartId).
+		setString("coupon_id", coupon.CouponId).
+		setString("coupon_name", coupon.CouponName).
+		setFloat("discount", coupon.Discount).
+		setString("reason", coupon.Reason)
+}
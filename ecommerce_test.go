@@ -0,0 +1,103 @@
+package analytics
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEcommerceEvents(t *testing.T) {
+	product := Product{ID: "507f1f77bcf86cd799439011", SKU: "G-32", Name: "Monopoly", Price: 18.99}
+
+	tests := []struct {
+		event SpecEvent
+		ref   string
+	}{
+		{
+			event: ProductsSearched{Query: "blue hotpants"},
+			ref:   `{"event":"Products Searched","properties":{"query":"blue hotpants"}}`,
+		},
+		{
+			event: ProductListViewed{ListId: "hot_deals_1", Products: []Product{product}},
+			ref:   `{"event":"Product List Viewed","properties":{"list_id":"hot_deals_1","products":[{"name":"Monopoly","price":18.99,"product_id":"507f1f77bcf86cd799439011","sku":"G-32"}]}}`,
+		},
+		{
+			event: ProductViewed{Product: product, Currency: "USD"},
+			ref:   `{"event":"Product Viewed","properties":{"currency":"USD","name":"Monopoly","price":18.99,"product_id":"507f1f77bcf86cd799439011","sku":"G-32"}}`,
+		},
+		{
+			event: ProductAdded{CartId: "skdjsidjsdkdj29j", Product: product},
+			ref:   `{"event":"Product Added","properties":{"cart_id":"skdjsidjsdkdj29j","name":"Monopoly","price":18.99,"product_id":"507f1f77bcf86cd799439011","sku":"G-32"}}`,
+		},
+		{
+			event: CartViewed{CartId: "skdjsidjsdkdj29j"},
+			ref:   `{"event":"Cart Viewed","properties":{"cart_id":"skdjsidjsdkdj29j"}}`,
+		},
+		{
+			event: CheckoutStarted{Revenue: 25, Currency: "USD"},
+			ref:   `{"event":"Checkout Started","properties":{"currency":"USD","revenue":25}}`,
+		},
+		{
+			event: OrderCompleted{OrderId: "50314b8e9bcf000000000000", Total: 27.5, Coupon: "hasbros", Products: []Product{{ID: "1"}}},
+			ref:   `{"event":"Order Completed","properties":{"coupon":"hasbros","order_id":"50314b8e9bcf000000000000","products":[{"product_id":"1"}],"total":27.5}}`,
+		},
+		{
+			event: OrderRefunded{OrderId: "50314b8e9bcf000000000000"},
+			ref:   `{"event":"Order Refunded","properties":{"order_id":"50314b8e9bcf000000000000"}}`,
+		},
+		{
+			event: CheckoutStepCompleted{CheckoutId: "fksdjfsdjfisjf9sdfjsd9f", Step: 2, ShippingMethod: "Fedex"},
+			ref:   `{"event":"Checkout Step Completed","properties":{"checkout_id":"fksdjfsdjfisjf9sdfjsd9f","shipping_method":"Fedex","step":2}}`,
+		},
+		{
+			event: CouponDenied{OrderId: "ek3a", CouponId: "may_deals_2016", Reason: "Coupon expired"},
+			ref:   `{"event":"Coupon Denied","properties":{"coupon_id":"may_deals_2016","order_id":"ek3a","reason":"Coupon expired"}}`,
+		},
+	}
+
+	for _, test := range tests {
+		track, err := SpecTrack(Track{}, test.event)
+		if err != nil {
+			t.Errorf("%T: %s", test.event, err)
+			continue
+		}
+
+		b, _ := json.Marshal(struct {
+			Event      string     `json:"event"`
+			Properties Properties `json:"properties"`
+		}{track.Event, track.Properties})
+
+		if s := string(b); s != test.ref {
+			t.Errorf("%T: invalid track message:\n- expected %s\n- found: %s", test.event, test.ref, s)
+		}
+	}
+}
+
+func TestEcommerceEventsValidate(t *testing.T) {
+	tests := []struct {
+		event SpecEvent
+		ref   FieldError
+	}{
+		{
+			event: ProductViewed{},
+			ref:   FieldError{Type: "analytics.ProductViewed", Name: "Product.ID", Value: ""},
+		},
+		{
+			event: CartViewed{Products: []Product{{ID: "1"}, {SKU: "G-32"}}},
+			ref:   FieldError{Type: "analytics.CartViewed", Name: "Products[1].ID", Value: ""},
+		},
+		{
+			event: OrderCompleted{Total: 10},
+			ref:   FieldError{Type: "analytics.OrderCompleted", Name: "OrderId", Value: ""},
+		},
+		{
+			event: OrderCancelled{OrderId: "A", Products: []Product{{}}},
+			ref:   FieldError{Type: "analytics.OrderCancelled", Name: "Products[0].ID", Value: ""},
+		},
+	}
+
+	for _, test := range tests {
+		if err := test.event.Validate(); err != test.ref {
+			t.Errorf("%T: invalid validation error:\n- expected %v\n- found: %v", test.event, test.ref, err)
+		}
+	}
+}
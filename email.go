@@ -0,0 +1,134 @@
+package analytics
+
+// This file defines the events of the email spec as described in
+// https://segment.com/docs/connections/spec/email/
+//
+// Email events also fill the campaign of the message context, with the name of
+// the campaign and "email" as medium.
+
+// This type represents the properties of the email events.
+type Email struct {
+	EmailId      string
+	EmailSubject string
+	CampaignId   string
+	CampaignName string
+
+	// The URL of the link that was clicked, only used by the Email Link
+	// Clicked event.
+	LinkURL string
+
+	// The id of the list the recipient unsubscribed from, only used by the
+	// Unsubscribed event.
+	ListId string
+}
+
+// This type represents the properties of the Email Delivered event.
+type EmailDelivered Email
+
+// This type represents the properties of the Email Opened event.
+type EmailOpened Email
+
+// This type represents the properties of the Email Link Clicked event.
+type EmailLinkClicked Email
+
+// This type represents the properties of the Email Bounced event.
+type EmailBounced Email
+
+// This type represents the properties of the Email Marked as Spam event.
+type EmailMarkedAsSpam Email
+
+// This type represents the properties of the Unsubscribed event.
+type Unsubscribed Email
+
+func (e EmailDelivered) Validate() error {
+	return validateEmail("analytics.EmailDelivered", Email(e))
+}
+
+func (e EmailDelivered) Apply(msg *Track) {
+	msg.Event = "Email Delivered"
+	msg.setEmail(Email(e))
+}
+
+func (e EmailOpened) Validate() error {
+	return validateEmail("analytics.EmailOpened", Email(e))
+}
+
+func (e EmailOpened) Apply(msg *Track) {
+	msg.Event = "Email Opened"
+	msg.setEmail(Email(e))
+}
+
+func (e EmailLinkClicked) Validate() error {
+	if err := validateEmail("analytics.EmailLinkClicked", Email(e)); err != nil {
+		return err
+	}
+
+	if len(e.LinkURL) == 0 {
+		return FieldError{
+			Type:  "analytics.EmailLinkClicked",
+			Name:  "LinkURL",
+			Value: e.LinkURL,
+		}
+	}
+
+	return nil
+}
+
+func (e EmailLinkClicked) Apply(msg *Track) {
+	msg.Event = "Email Link Clicked"
+	msg.setEmail(Email(e))
+}
+
+func (e EmailBounced) Validate() error {
+	return validateEmail("analytics.EmailBounced", Email(e))
+}
+
+func (e EmailBounced) Apply(msg *Track) {
+	msg.Event = "Email Bounced"
+	msg.setEmail(Email(e))
+}
+
+func (e EmailMarkedAsSpam) Validate() error {
+	return validateEmail("analytics.EmailMarkedAsSpam", Email(e))
+}
+
+func (e EmailMarkedAsSpam) Apply(msg *Track) {
+	msg.Event = "Email Marked as Spam"
+	msg.setEmail(Email(e))
+}
+
+func (e Unsubscribed) Validate() error {
+	return validateEmail("analytics.Unsubscribed", Email(e))
+}
+
+func (e Unsubscribed) Apply(msg *Track) {
+	msg.Event = "Unsubscribed"
+	msg.setEmail(Email(e))
+}
+
+func validateEmail(typ string, email Email) error {
+	if len(email.EmailId) == 0 {
+		return FieldError{
+			Type:  typ,
+			Name:  "EmailId",
+			Value: email.EmailId,
+		}
+	}
+	return nil
+}
+
+func (msg *Track) setEmail(email Email) {
+	msg.Properties.
+		setString("email_id", email.EmailId).
+		setString("email_subject", email.EmailSubject).
+		setString("campaign_id", email.CampaignId).
+		setString("campaign_name", email.CampaignName).
+		setString("link_url", email.LinkURL).
+		setString("list_id", email.ListId)
+
+	if len(email.CampaignName) != 0 {
+		msg.Context.Campaign.Name = email.CampaignName
+	}
+
+	msg.Context.Campaign.Medium = "email"
+}
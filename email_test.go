@@ -0,0 +1,95 @@
+package analytics
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEmailEvents(t *testing.T) {
+	email := Email{
+		EmailId:      "18vzF7u6",
+		EmailSubject: "Welcome!",
+		CampaignId:   "123",
+		CampaignName: "Welcome series",
+	}
+
+	clicked := email
+	clicked.LinkURL = "https://example.com/start"
+
+	unsubscribed := email
+	unsubscribed.ListId = "newsletter"
+
+	tests := []struct {
+		event SpecEvent
+		ref   string
+	}{
+		{
+			event: EmailDelivered(email),
+			ref:   `{"event":"Email Delivered","campaign":{"name":"Welcome series","medium":"email"},"properties":{"campaign_id":"123","campaign_name":"Welcome series","email_id":"18vzF7u6","email_subject":"Welcome!"}}`,
+		},
+		{
+			event: EmailOpened(email),
+			ref:   `{"event":"Email Opened","campaign":{"name":"Welcome series","medium":"email"},"properties":{"campaign_id":"123","campaign_name":"Welcome series","email_id":"18vzF7u6","email_subject":"Welcome!"}}`,
+		},
+		{
+			event: EmailLinkClicked(clicked),
+			ref:   `{"event":"Email Link Clicked","campaign":{"name":"Welcome series","medium":"email"},"properties":{"campaign_id":"123","campaign_name":"Welcome series","email_id":"18vzF7u6","email_subject":"Welcome!","link_url":"https://example.com/start"}}`,
+		},
+		{
+			event: EmailBounced{EmailId: "18vzF7u6"},
+			ref:   `{"event":"Email Bounced","campaign":{"medium":"email"},"properties":{"email_id":"18vzF7u6"}}`,
+		},
+		{
+			event: EmailMarkedAsSpam(email),
+			ref:   `{"event":"Email Marked as Spam","campaign":{"name":"Welcome series","medium":"email"},"properties":{"campaign_id":"123","campaign_name":"Welcome series","email_id":"18vzF7u6","email_subject":"Welcome!"}}`,
+		},
+		{
+			event: Unsubscribed(unsubscribed),
+			ref:   `{"event":"Unsubscribed","campaign":{"name":"Welcome series","medium":"email"},"properties":{"campaign_id":"123","campaign_name":"Welcome series","email_id":"18vzF7u6","email_subject":"Welcome!","list_id":"newsletter"}}`,
+		},
+	}
+
+	for _, test := range tests {
+		track, err := SpecTrack(Track{}, test.event)
+		if err != nil {
+			t.Errorf("%T: %s", test.event, err)
+			continue
+		}
+
+		b, _ := json.Marshal(struct {
+			Event      string       `json:"event"`
+			Campaign   CampaignInfo `json:"campaign"`
+			Properties Properties   `json:"properties"`
+		}{track.Event, track.Context.Campaign, track.Properties})
+
+		if s := string(b); s != test.ref {
+			t.Errorf("%T: invalid track message:\n- expected %s\n- found: %s", test.event, test.ref, s)
+		}
+	}
+}
+
+func TestEmailEventsValidate(t *testing.T) {
+	tests := []struct {
+		event SpecEvent
+		ref   FieldError
+	}{
+		{
+			event: EmailDelivered{},
+			ref:   FieldError{Type: "analytics.EmailDelivered", Name: "EmailId", Value: ""},
+		},
+		{
+			event: EmailLinkClicked{EmailId: "18vzF7u6"},
+			ref:   FieldError{Type: "analytics.EmailLinkClicked", Name: "LinkURL", Value: ""},
+		},
+		{
+			event: Unsubscribed{},
+			ref:   FieldError{Type: "analytics.Unsubscribed", Name: "EmailId", Value: ""},
+		},
+	}
+
+	for _, test := range tests {
+		if err := test.event.Validate(); err != test.ref {
+			t.Errorf("%T: invalid validation error:\n- expected %v\n- found: %v", test.event, test.ref, err)
+		}
+	}
+}
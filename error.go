@@ -3,6 +3,7 @@ package analytics
 import (
 	"errors"
 	"fmt"
+	"net/http"
 )
 
 // Returned by the `NewWithConfig` function when the one of the configuration
@@ -44,6 +45,61 @@ func (e FieldError) Error() string {
 	return fmt.Sprintf("%s.%s: invalid field value: %#v", e.Type, e.Name, e.Value)
 }
 
+// This type is used to report responses with an unexpected status code from the
+// API, it is passed to the retry policy and to the failure callback when the
+// client gives up on sending messages.
+type HTTPError struct {
+
+	// The status code of the response.
+	StatusCode int
+
+	// The headers of the response.
+	Header http.Header
+
+	// The body of the response, usually a JSON object describing the error.
+	Body []byte
+}
+
+func (e HTTPError) Error() string {
+	return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// This type is used to notify the client callbacks that a message was dropped by
+// one of the middleware set in the client configuration.
+type DroppedError struct {
+
+	// The error returned by the middleware to explain why the message was
+	// dropped, nil if the middleware didn't give a reason.
+	Reason error
+}
+
+func (e DroppedError) Error() string {
+	if e.Reason == nil {
+		return "analytics.Middleware: message dropped"
+	}
+	return "analytics.Middleware: message dropped: " + e.Reason.Error()
+}
+
+// Returned by the `CloseContext` method of clients when some of the messages
+// that were queued when the client was closed could not be delivered.
+// The counts only include messages that completed during the shutdown.
+type CloseError struct {
+
+	// The number of messages successfully sent to the API.
+	Delivered int
+
+	// The number of messages that failed to be sent to the API.
+	Failed int
+
+	// The number of messages that were given up on because the context passed
+	// to `CloseContext` was canceled.
+	Abandoned int
+}
+
+func (e CloseError) Error() string {
+	return fmt.Sprintf("analytics.Client.CloseContext: %d messages delivered, %d failed, %d abandoned", e.Delivered, e.Failed, e.Abandoned)
+}
+
 var (
 	// This error is returned by methods of the `Client` interface when they are
 	// called after the client was already closed.
@@ -57,4 +113,15 @@ var (
 	// failed because the JSON representation of a message exceeded the upper
 	// limit.
 	ErrMessageTooBig = errors.New("the message exceeds the maximum allowed size")
+
+	// This error is returned by `Enqueue` or passed to the client callbacks
+	// when a message was dropped because the client queue was full, see
+	// `OverflowPolicy` for details.
+	ErrQueueFull = errors.New("the message was dropped because the client queue was full")
+
+	// This error is used to notify the client callbacks that a message was not
+	// sent because the client was closed before it could be delivered, either
+	// because the context passed to `CloseContext` was canceled or because
+	// uploads were paused at the API's request when `Close` was called.
+	ErrAbandoned = errors.New("the message was abandoned because the client was closed before it could be sent")
 )
@@ -25,3 +25,35 @@ func TestFieldError(t *testing.T) {
 		t.Error("invalid error message returned by field error:", s)
 	}
 }
+
+func TestCloseError(t *testing.T) {
+	e := CloseError{
+		Delivered: 1,
+		Failed:    2,
+		Abandoned: 3,
+	}
+
+	if s := e.Error(); s != "analytics.Client.CloseContext: 1 messages delivered, 2 failed, 3 abandoned" {
+		t.Error("invalid error message returned by close error:", s)
+	}
+}
+
+func TestHTTPError(t *testing.T) {
+	e := HTTPError{
+		StatusCode: 429,
+	}
+
+	if s := e.Error(); s != "429 Too Many Requests" {
+		t.Error("invalid error message returned by HTTP error:", s)
+	}
+}
+
+func TestDroppedError(t *testing.T) {
+	if s := (DroppedError{}).Error(); s != "analytics.Middleware: message dropped" {
+		t.Error("invalid error message returned by dropped error:", s)
+	}
+
+	if s := (DroppedError{Reason: testError}).Error(); s != "analytics.Middleware: message dropped: test error" {
+		t.Error("invalid error message returned by dropped error:", s)
+	}
+}
@@ -7,3 +7,11 @@ require (
 	github.com/segmentio/backo-go v1.0.0
 	github.com/segmentio/conf v1.2.0
 )
+
+require (
+	github.com/segmentio/go-snakecase v1.1.0 // indirect
+	github.com/segmentio/objconv v1.0.1 // indirect
+	gopkg.in/go-playground/mold.v2 v2.2.0 // indirect
+	gopkg.in/validator.v2 v2.0.0-20180514200540-135c24b11c19 // indirect
+	gopkg.in/yaml.v2 v2.2.1 // indirect
+)
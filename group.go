@@ -38,5 +38,5 @@ func (msg Group) Validate() error {
 		}
 	}
 
-	return nil
+	return msg.Traits.validateTyped()
 }
@@ -29,5 +29,5 @@ func (msg Identify) Validate() error {
 		}
 	}
 
-	return nil
+	return msg.Traits.validateTyped()
 }
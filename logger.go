@@ -1,8 +1,10 @@
 package analytics
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strings"
 )
 
 // Instances of types implementing this interface can be used to define where
@@ -22,6 +24,85 @@ type Logger interface {
 	Errorf(format string, args ...interface{})
 }
 
+// Loggers that also implement this interface receive log messages from the
+// analytics clients as a message and a list of typed fields, instead of a
+// formatted string.
+//
+// Clients use the following field keys:
+//
+//	batchId       the message id of the batch being sent
+//	attempt       the index of the upload attempt, starting at zero
+//	status        the HTTP status code of the response
+//	messageCount  the number of messages concerned by the log message
+//	error         the error that caused the log message
+//	message       the message concerned by the log message
+//
+// Other fields may be added in future versions.
+type StructuredLogger interface {
+	Logger
+
+	// Analytics clients call this method instead of `Logf` and `Errorf` when
+	// the logger implements it. The method is only called with levels that are
+	// greater than or equal to the `LogLevel` of the client configuration.
+	Log(level LogLevel, msg string, fields ...Field)
+}
+
+// This type represents the severity of log messages.
+//
+// The values of log levels match the ones of the log/slog package.
+type LogLevel int
+
+const (
+	// Detailed messages about the operations performed by clients, these are
+	// only logged when the level is enabled in the client configuration.
+	LevelDebug LogLevel = -4
+
+	// Regular messages about the operations performed by clients, logged by
+	// `Logf` on loggers that only implement the `Logger` interface.
+	LevelInfo LogLevel = 0
+
+	// Errors encountered by clients, logged by `Errorf` on loggers that only
+	// implement the `Logger` interface.
+	LevelError LogLevel = 8
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelError:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("LogLevel(%d)", int(l))
+	}
+}
+
+// This type represents a typed field attached to log messages.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Formats a log message with its fields as key=value pairs, this is used to
+// produce log lines for loggers that don't implement `StructuredLogger`.
+func formatLog(msg string, fields []Field) string {
+	if len(fields) == 0 {
+		return msg
+	}
+
+	s := strings.Builder{}
+	s.WriteString(msg)
+	s.WriteString(" -")
+
+	for _, f := range fields {
+		fmt.Fprintf(&s, " %s=%v", f.Key, f.Value)
+	}
+
+	return s.String()
+}
+
 // This function instantiate an object that statisfies the analytics.Logger
 // interface and send logs to standard logger passed as argument.
 func StdLogger(logger *log.Logger) Logger {
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"log"
+	"strings"
 	"testing"
 )
 
@@ -34,3 +35,68 @@ test ERROR: something went wrong!
 		t.Errorf("invalid logs from standard logger:\n- expected: %s\n- found: %s", ref, res)
 	}
 }
+
+// Instances of this type record the structured log messages they receive.
+type testStructuredLogger struct {
+	testLogger
+	logs chan string
+}
+
+func (l testStructuredLogger) Log(level LogLevel, msg string, fields ...Field) {
+	l.logs <- level.String() + " " + formatLog(msg, fields)
+}
+
+func TestFormatLog(t *testing.T) {
+	const ref = "sending request failed - attempt=1 error=timeout"
+
+	if s := formatLog("sending request failed", []Field{{"attempt", 1}, {"error", errors.New("timeout")}}); s != ref {
+		t.Errorf("invalid log line:\n- expected: %s\n- found: %s", ref, s)
+	}
+}
+
+func TestClientStructuredLogger(t *testing.T) {
+	logs := make(chan string, 100)
+
+	client, _ := NewWithConfig("0123456789", Config{
+		Logger:    testStructuredLogger{testLogger{t.Logf, t.Logf}, logs},
+		Transport: testTransportBadRequest,
+		BatchSize: 1,
+	})
+
+	client.Enqueue(Track{UserId: "A", Event: "B"})
+	client.Close()
+	close(logs)
+
+	var found bool
+
+	for log := range logs {
+		if strings.HasPrefix(log, "DEBUG") {
+			t.Error("debug messages must not be logged by default:", log)
+		}
+		if strings.HasPrefix(log, "INFO response received - batchId=") && strings.Contains(log, "attempt=0 status=400") {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Error("the response was not logged with its fields")
+	}
+}
+
+func TestClientLogLevel(t *testing.T) {
+	for _, config := range []Config{
+		{LogLevel: LevelDebug},
+		{Verbose: true},
+	} {
+		logs := make(chan string, 100)
+
+		config.Logger = testStructuredLogger{testLogger{t.Logf, t.Logf}, logs}
+		client, _ := NewWithConfig("0123456789", config)
+		client.Close()
+		close(logs)
+
+		if log := <-logs; !strings.HasPrefix(log, "DEBUG") {
+			t.Error("debug messages should have been logged:", log)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package analytics
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -69,6 +70,10 @@ type batch struct {
 type message struct {
 	msg  Message
 	json []byte
+
+	// The sequence number of the message in the client storage, zero if the
+	// message wasn't persisted.
+	seq uint64
 }
 
 func makeMessage(m Message, maxBytes int) (msg message, err error) {
@@ -82,6 +87,52 @@ func makeMessage(m Message, maxBytes int) (msg message, err error) {
 	return
 }
 
+// Reconstructs a message from its JSON representation, the type of the message
+// is determined by the value of its "type" field.
+// This is used to recover messages from the client storage, note that fields
+// which aren't decoded (like the `Extra` map of the context) are still part of
+// the JSON representation that gets sent to the API.
+func decodeMessage(b []byte) (msg Message, err error) {
+	var typ struct {
+		Type string `json:"type"`
+	}
+
+	if err = json.Unmarshal(b, &typ); err != nil {
+		return
+	}
+
+	switch typ.Type {
+	case "alias":
+		m := Alias{}
+		err = json.Unmarshal(b, &m)
+		msg = m
+	case "group":
+		m := Group{}
+		err = json.Unmarshal(b, &m)
+		msg = m
+	case "identify":
+		m := Identify{}
+		err = json.Unmarshal(b, &m)
+		msg = m
+	case "page":
+		m := Page{}
+		err = json.Unmarshal(b, &m)
+		msg = m
+	case "screen":
+		m := Screen{}
+		err = json.Unmarshal(b, &m)
+		msg = m
+	case "track":
+		m := Track{}
+		err = json.Unmarshal(b, &m)
+		msg = m
+	default:
+		err = fmt.Errorf("messages with unknown types cannot be decoded: %q", typ.Type)
+	}
+
+	return
+}
+
 func (m message) MarshalJSON() ([]byte, error) {
 	return m.json, nil
 }
@@ -90,3 +90,29 @@ func TestMakeMessageTooBig(t *testing.T) {
 		t.Error("invalid error returned when creating a message bigger than the limit:", err)
 	}
 }
+
+func TestDecodeMessage(t *testing.T) {
+	track := Track{
+		Type:       "track",
+		MessageId:  "A",
+		UserId:     "1",
+		Event:      "Download",
+		Timestamp:  mockTime(),
+		Properties: Properties{"version": "1.1.0"},
+	}
+
+	m, _ := makeMessage(track, maxMessageBytes)
+
+	if msg, err := decodeMessage(m.json); err != nil {
+		t.Error("failed to decode track message:", err)
+
+	} else if !reflect.DeepEqual(msg, track) {
+		t.Errorf("invalid message decoded:\n- expected %#v\n- found: %#v", track, msg)
+	}
+}
+
+func TestDecodeMessageUnknownType(t *testing.T) {
+	if _, err := decodeMessage([]byte(`{"type":"custom"}`)); err == nil {
+		t.Error("no error returned when decoding a message with an unknown type")
+	}
+}
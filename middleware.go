@@ -0,0 +1,30 @@
+package analytics
+
+// Middleware functions are used to process messages before they are queued by a
+// client, for example to enrich them with data shared by all messages or to
+// filter out messages that should not be sent.
+//
+// Middleware are called by `Enqueue` after the type, message id and timestamp
+// of the message were set, and before the message is validated. They return
+// the message to pass to the next middleware, which may be the original
+// message, a modified copy of it, or a different message altogether.
+// Returning a nil message or a non-nil error drops the message, it is then
+// reported to the client callback with a `DroppedError` carrying the error.
+//
+// Middleware are called on the goroutine that queued the message, they must be
+// safe to use concurrently.
+type Middleware func(Message) (Message, error)
+
+// Runs the message through the client middleware, in the order they were set in
+// the configuration. The method stops and returns a nil message as soon as a
+// middleware drops the message.
+func (c *client) applyMiddleware(msg Message) (Message, error) {
+	for _, m := range c.Middleware {
+		var err error
+
+		if msg, err = m(msg); msg == nil || err != nil {
+			return nil, err
+		}
+	}
+	return msg, nil
+}
@@ -0,0 +1,124 @@
+package analytics
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	c := &client{
+		Config: Config{
+			Middleware: []Middleware{
+				func(m Message) (Message, error) {
+					t := m.(Track)
+					t.Properties = Properties{"order": []string{"A"}}
+					return t, nil
+				},
+				func(m Message) (Message, error) {
+					t := m.(Track)
+					t.Properties["order"] = append(t.Properties["order"].([]string), "B")
+					return t, nil
+				},
+			},
+		},
+	}
+
+	msg, err := c.applyMiddleware(Track{UserId: "1", Event: "A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref := Track{UserId: "1", Event: "A", Properties: Properties{"order": []string{"A", "B"}}}
+
+	if !reflect.DeepEqual(msg, ref) {
+		t.Errorf("invalid message returned by middleware:\n- expected %#v\n- found: %#v", ref, msg)
+	}
+}
+
+func TestMiddlewareDrop(t *testing.T) {
+	reason := errors.New("test user")
+	called := false
+
+	c := &client{
+		Config: Config{
+			Middleware: []Middleware{
+				func(m Message) (Message, error) { return nil, reason },
+				func(m Message) (Message, error) { called = true; return m, nil },
+			},
+		},
+	}
+
+	if msg, err := c.applyMiddleware(Track{UserId: "1", Event: "A"}); msg != nil || err != reason {
+		t.Errorf("invalid result returned for a dropped message: %v, %v", msg, err)
+	}
+
+	if called {
+		t.Error("middleware should not be called after the message was dropped")
+	}
+}
+
+func TestClientMiddleware(t *testing.T) {
+	var ref = fixture("test-enqueue-track.json")
+
+	body, server := mockServer()
+	defer server.Close()
+
+	client, _ := NewWithConfig("h97jamjwbh", Config{
+		Endpoint:  server.URL,
+		Logger:    t,
+		BatchSize: 1,
+		Middleware: []Middleware{
+			// The message is invalid until the middleware sets its user id.
+			func(m Message) (Message, error) {
+				t := m.(Track)
+				t.UserId = "123456"
+				return &t, nil
+			},
+		},
+		now: mockTime,
+		uid: mockId,
+	})
+	defer client.Close()
+
+	if err := client.Enqueue(Track{
+		Event: "Download",
+		Properties: Properties{
+			"application": "Segment Desktop",
+			"version":     "1.1.0",
+			"platform":    "osx",
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if res := string(<-body); ref != res {
+		t.Errorf("invalid response:\n- expected %s\n- received: %s", ref, res)
+	}
+}
+
+func TestClientMiddlewareDrop(t *testing.T) {
+	errchan := make(chan error, 1)
+	reason := errors.New("test user")
+
+	client, _ := NewWithConfig("0123456789", Config{
+		Logger: testLogger{t.Logf, t.Logf},
+		Callback: testCallback{
+			nil,
+			func(m Message, e error) { errchan <- e },
+		},
+		Middleware: []Middleware{
+			func(m Message) (Message, error) { return nil, reason },
+		},
+		Transport: testTransportOK,
+	})
+	defer client.Close()
+
+	if err := client.Enqueue(Track{UserId: "A", Event: "B"}); err != nil {
+		t.Error("dropping a message should not fail the call to Enqueue:", err)
+	}
+
+	if err := <-errchan; err != (DroppedError{Reason: reason}) {
+		t.Error("invalid error reported for a dropped message:", err)
+	}
+}
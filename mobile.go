@@ -0,0 +1,216 @@
+package analytics
+
+// This file defines the events of the mobile spec as described in
+// https://segment.com/docs/connections/spec/mobile/
+//
+// Mobile events are usually sent by the device itself, programs that send them
+// on behalf of a device set the `App`, `Device` and `OS` fields of the events,
+// which are copied to the context of the track message when they are not
+// empty. The application version and build are also set as properties of the
+// events that the spec defines them on.
+
+// This type represents the properties of the Application Installed event.
+type ApplicationInstalled struct {
+	App    AppInfo
+	Device DeviceInfo
+	OS     OSInfo
+}
+
+// This type represents the properties of the Application Updated event.
+type ApplicationUpdated struct {
+	App             AppInfo
+	Device          DeviceInfo
+	OS              OSInfo
+	PreviousVersion string
+	PreviousBuild   string
+}
+
+// This type represents the properties of the Application Opened event.
+type ApplicationOpened struct {
+	App                  AppInfo
+	Device               DeviceInfo
+	OS                   OSInfo
+	FromBackground       bool
+	ReferringApplication string
+	URL                  string
+}
+
+// This type represents the properties of the Application Backgrounded event.
+type ApplicationBackgrounded struct {
+	App    AppInfo
+	Device DeviceInfo
+	OS     OSInfo
+}
+
+// This type represents the properties of the Application Crashed event.
+type ApplicationCrashed struct {
+	App    AppInfo
+	Device DeviceInfo
+	OS     OSInfo
+}
+
+// This type represents the properties of the Deep Link Opened event.
+type DeepLinkOpened struct {
+	App      AppInfo
+	Device   DeviceInfo
+	OS       OSInfo
+	Provider string
+	URL      string
+}
+
+// This type represents the properties of the Push Notification Received event.
+type PushNotificationReceived struct {
+	App      AppInfo
+	Device   DeviceInfo
+	OS       OSInfo
+	Campaign CampaignInfo
+}
+
+// This type represents the properties of the Push Notification Tapped event.
+type PushNotificationTapped struct {
+	App      AppInfo
+	Device   DeviceInfo
+	OS       OSInfo
+	Action   string
+	Campaign CampaignInfo
+}
+
+func (e ApplicationInstalled) Validate() error {
+	return validateAppVersion("analytics.ApplicationInstalled", e.App)
+}
+
+func (e ApplicationInstalled) Apply(msg *Track) {
+	msg.Event = "Application Installed"
+	msg.Context.setMobile(e.App, e.Device, e.OS)
+	msg.Properties.setAppVersion(e.App)
+}
+
+func (e ApplicationUpdated) Validate() error {
+	if err := validateAppVersion("analytics.ApplicationUpdated", e.App); err != nil {
+		return err
+	}
+
+	if len(e.PreviousVersion) == 0 {
+		return FieldError{
+			Type:  "analytics.ApplicationUpdated",
+			Name:  "PreviousVersion",
+			Value: e.PreviousVersion,
+		}
+	}
+
+	return nil
+}
+
+func (e ApplicationUpdated) Apply(msg *Track) {
+	msg.Event = "Application Updated"
+	msg.Context.setMobile(e.App, e.Device, e.OS)
+	msg.Properties.
+		setString("previous_version", e.PreviousVersion).
+		setString("previous_build", e.PreviousBuild).
+		setAppVersion(e.App)
+}
+
+func (e ApplicationOpened) Validate() error {
+	return validateAppVersion("analytics.ApplicationOpened", e.App)
+}
+
+func (e ApplicationOpened) Apply(msg *Track) {
+	msg.Event = "Application Opened"
+	msg.Context.setMobile(e.App, e.Device, e.OS)
+	msg.Properties.
+		setAppVersion(e.App).
+		setString("referring_application", e.ReferringApplication).
+		setString("url", e.URL)
+	msg.Properties["from_background"] = e.FromBackground
+}
+
+func (e ApplicationBackgrounded) Validate() error { return nil }
+
+func (e ApplicationBackgrounded) Apply(msg *Track) {
+	msg.Event = "Application Backgrounded"
+	msg.Context.setMobile(e.App, e.Device, e.OS)
+}
+
+func (e ApplicationCrashed) Validate() error { return nil }
+
+func (e ApplicationCrashed) Apply(msg *Track) {
+	msg.Event = "Application Crashed"
+	msg.Context.setMobile(e.App, e.Device, e.OS)
+}
+
+func (e DeepLinkOpened) Validate() error {
+	if len(e.URL) == 0 {
+		return FieldError{
+			Type:  "analytics.DeepLinkOpened",
+			Name:  "URL",
+			Value: e.URL,
+		}
+	}
+	return nil
+}
+
+func (e DeepLinkOpened) Apply(msg *Track) {
+	msg.Event = "Deep Link Opened"
+	msg.Context.setMobile(e.App, e.Device, e.OS)
+	msg.Properties.
+		setString("provider", e.Provider).
+		setString("url", e.URL)
+}
+
+func (e PushNotificationReceived) Validate() error { return nil }
+
+func (e PushNotificationReceived) Apply(msg *Track) {
+	msg.Event = "Push Notification Received"
+	msg.Context.setMobile(e.App, e.Device, e.OS)
+	msg.Properties.setCampaign(e.Campaign)
+}
+
+func (e PushNotificationTapped) Validate() error { return nil }
+
+func (e PushNotificationTapped) Apply(msg *Track) {
+	msg.Event = "Push Notification Tapped"
+	msg.Context.setMobile(e.App, e.Device, e.OS)
+	msg.Properties.
+		setString("action", e.Action).
+		setCampaign(e.Campaign)
+}
+
+func validateAppVersion(typ string, app AppInfo) error {
+	if len(app.Version) == 0 {
+		return FieldError{
+			Type:  typ,
+			Name:  "App.Version",
+			Value: app.Version,
+		}
+	}
+	return nil
+}
+
+// Sets the app, device and OS of the context, values of the context are only
+// replaced by values that are not empty.
+func (ctx *Context) setMobile(app AppInfo, device DeviceInfo, os OSInfo) {
+	if app != (AppInfo{}) {
+		ctx.App = app
+	}
+
+	if device != (DeviceInfo{}) {
+		ctx.Device = device
+	}
+
+	if os != (OSInfo{}) {
+		ctx.OS = os
+	}
+}
+
+func (p Properties) setAppVersion(app AppInfo) Properties {
+	return p.
+		setString("version", app.Version).
+		setString("build", app.Build)
+}
+
+func (p Properties) setCampaign(campaign CampaignInfo) Properties {
+	if campaign != (CampaignInfo{}) {
+		p["campaign"] = campaign
+	}
+	return p
+}
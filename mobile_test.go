@@ -0,0 +1,113 @@
+package analytics
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMobileEvents(t *testing.T) {
+	app := AppInfo{Name: "Segment", Version: "3.0.1", Build: "3"}
+	device := DeviceInfo{Id: "B5372DB0-C21E-11E4-8DFC-AA07A5B093DB", Manufacturer: "Apple", Model: "iPhone7,2"}
+	os := OSInfo{Name: "iPhone OS", Version: "8.1.3"}
+
+	tests := []struct {
+		event SpecEvent
+		ref   string
+	}{
+		{
+			event: ApplicationInstalled{App: app, Device: device, OS: os},
+			ref:   `{"event":"Application Installed","context":{"app":{"name":"Segment","version":"3.0.1","build":"3"},"device":{"id":"B5372DB0-C21E-11E4-8DFC-AA07A5B093DB","manufacturer":"Apple","model":"iPhone7,2"},"os":{"name":"iPhone OS","version":"8.1.3"}},"properties":{"build":"3","version":"3.0.1"}}`,
+		},
+		{
+			event: ApplicationUpdated{App: app, PreviousVersion: "3.0.0", PreviousBuild: "2"},
+			ref:   `{"event":"Application Updated","context":{"app":{"name":"Segment","version":"3.0.1","build":"3"}},"properties":{"build":"3","previous_build":"2","previous_version":"3.0.0","version":"3.0.1"}}`,
+		},
+		{
+			event: ApplicationOpened{App: app, ReferringApplication: "GMail", URL: "app://gmail"},
+			ref:   `{"event":"Application Opened","context":{"app":{"name":"Segment","version":"3.0.1","build":"3"}},"properties":{"build":"3","from_background":false,"referring_application":"GMail","url":"app://gmail","version":"3.0.1"}}`,
+		},
+		{
+			event: ApplicationBackgrounded{OS: os},
+			ref:   `{"event":"Application Backgrounded","context":{"os":{"name":"iPhone OS","version":"8.1.3"}},"properties":{}}`,
+		},
+		{
+			event: ApplicationCrashed{Device: device},
+			ref:   `{"event":"Application Crashed","context":{"device":{"id":"B5372DB0-C21E-11E4-8DFC-AA07A5B093DB","manufacturer":"Apple","model":"iPhone7,2"}},"properties":{}}`,
+		},
+		{
+			event: DeepLinkOpened{Provider: "Branch Metrics", URL: "app://landing"},
+			ref:   `{"event":"Deep Link Opened","context":{},"properties":{"provider":"Branch Metrics","url":"app://landing"}}`,
+		},
+		{
+			event: PushNotificationReceived{Campaign: CampaignInfo{Name: "Free Bananas", Medium: "Push", Source: "Vendor Name"}},
+			ref:   `{"event":"Push Notification Received","context":{},"properties":{"campaign":{"name":"Free Bananas","source":"Vendor Name","medium":"Push"}}}`,
+		},
+		{
+			event: PushNotificationTapped{Action: "Accept", Campaign: CampaignInfo{Name: "Free Bananas"}},
+			ref:   `{"event":"Push Notification Tapped","context":{},"properties":{"action":"Accept","campaign":{"name":"Free Bananas"}}}`,
+		},
+	}
+
+	for _, test := range tests {
+		track, err := SpecTrack(Track{}, test.event)
+		if err != nil {
+			t.Errorf("%T: %s", test.event, err)
+			continue
+		}
+
+		b, _ := json.Marshal(struct {
+			Event      string     `json:"event"`
+			Context    *Context   `json:"context"`
+			Properties Properties `json:"properties"`
+		}{track.Event, track.Context, track.Properties})
+
+		if s := string(b); s != test.ref {
+			t.Errorf("%T: invalid track message:\n- expected %s\n- found: %s", test.event, test.ref, s)
+		}
+	}
+}
+
+func TestMobileEventsKeepBaseContext(t *testing.T) {
+	base := Track{
+		Context: &Context{OS: OSInfo{Name: "Android", Version: "9"}},
+	}
+
+	track, err := SpecTrack(base, ApplicationInstalled{App: AppInfo{Version: "1.0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if track.Context.OS != base.Context.OS {
+		t.Error("empty fields of the event must not overwrite the base context:", track.Context.OS)
+	}
+}
+
+func TestMobileEventsValidate(t *testing.T) {
+	tests := []struct {
+		event SpecEvent
+		ref   FieldError
+	}{
+		{
+			event: ApplicationInstalled{},
+			ref:   FieldError{Type: "analytics.ApplicationInstalled", Name: "App.Version", Value: ""},
+		},
+		{
+			event: ApplicationUpdated{App: AppInfo{Version: "3.0.1"}},
+			ref:   FieldError{Type: "analytics.ApplicationUpdated", Name: "PreviousVersion", Value: ""},
+		},
+		{
+			event: ApplicationOpened{},
+			ref:   FieldError{Type: "analytics.ApplicationOpened", Name: "App.Version", Value: ""},
+		},
+		{
+			event: DeepLinkOpened{Provider: "Branch Metrics"},
+			ref:   FieldError{Type: "analytics.DeepLinkOpened", Name: "URL", Value: ""},
+		},
+	}
+
+	for _, test := range tests {
+		if err := test.event.Validate(); err != test.ref {
+			t.Errorf("%T: invalid validation error:\n- expected %v\n- found: %v", test.event, test.ref, err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package analytics
+
+import (
+	"context"
+	"time"
+)
+
+// Values of this type are used to configure what clients do when a message is
+// queued while the queue is already full, which happens when messages are
+// produced faster than they can be batched.
+type OverflowPolicy int
+
+const (
+	// Queuing a message blocks until there is room in the queue or the context
+	// passed to `EnqueueContext` is canceled, this is the default.
+	OverflowBlock OverflowPolicy = iota
+
+	// The message being queued is dropped and `ErrQueueFull` is returned.
+	OverflowDropNewest
+
+	// The oldest message in the queue is dropped to make room for the message
+	// being queued, the dropped message is reported to the callback with
+	// `ErrQueueFull`.
+	OverflowDropOldest
+
+	// Queuing a message blocks until there is room in the queue, or returns
+	// `ErrQueueFull` if the enqueue timeout set in the configuration expires
+	// first.
+	OverflowBlockWithTimeout
+)
+
+func (p OverflowPolicy) valid() bool {
+	return p >= OverflowBlock && p <= OverflowBlockWithTimeout
+}
+
+// Writes the message to the queue of messages picked up by the backend
+// goroutine, applying the overflow policy if the queue is full.
+// The method panics if the client was closed, callers are expected to recover
+// from it.
+func (c *client) queue(ctx context.Context, m message) error {
+	select {
+	case c.msgs <- m:
+		return nil
+	default:
+	}
+
+	switch c.OverflowPolicy {
+	case OverflowDropNewest:
+		c.debug("queue full, dropping newest message", Field{"message", m.msg})
+		c.counters.add(&c.counters.dropped, 1)
+		return ErrQueueFull
+
+	case OverflowDropOldest:
+		for {
+			select {
+			case c.msgs <- m:
+				return nil
+			default:
+			}
+
+			select {
+			case old, ok := <-c.msgs:
+				if ok {
+					c.error("queue full, dropping oldest message", Field{"message", old.msg})
+					c.notifyFailure([]message{old}, ErrQueueFull)
+					c.ack([]message{old})
+				}
+			default:
+			}
+		}
+
+	case OverflowBlockWithTimeout:
+		timer := time.NewTimer(c.EnqueueTimeout)
+		defer timer.Stop()
+
+		select {
+		case c.msgs <- m:
+			return nil
+		case <-timer.C:
+			c.counters.add(&c.counters.dropped, 1)
+			return ErrQueueFull
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+	default:
+		select {
+		case c.msgs <- m:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
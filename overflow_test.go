@@ -0,0 +1,100 @@
+package analytics
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Creates a client with a full queue of size 1 and no backend goroutine to
+// consume it.
+func newFullQueueClient(config Config) (*client, message) {
+	config.QueueSize = 1
+	c := &client{
+		Config:   makeConfig(config),
+		msgs:     make(chan message, 1),
+		counters: newCounters(),
+	}
+	m := message{msg: Track{UserId: "A", Event: "first"}}
+	c.msgs <- m
+	return c, m
+}
+
+func TestOverflowDropNewest(t *testing.T) {
+	c, m0 := newFullQueueClient(Config{
+		Logger:         testLogger{t.Logf, t.Logf},
+		OverflowPolicy: OverflowDropNewest,
+	})
+
+	if err := c.queue(context.Background(), message{msg: Track{UserId: "A", Event: "second"}}); err != ErrQueueFull {
+		t.Error("invalid error returned when queuing a message to a full queue:", err)
+	}
+
+	if m := <-c.msgs; !reflect.DeepEqual(m.msg, m0.msg) {
+		t.Error("the oldest message should have been kept in the queue:", m.msg)
+	}
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	dropped := make(chan Message, 1)
+	errchan := make(chan error, 1)
+
+	c, m0 := newFullQueueClient(Config{
+		Logger: testLogger{t.Logf, t.Logf},
+		Callback: testCallback{
+			nil,
+			func(m Message, e error) { dropped <- m; errchan <- e },
+		},
+		OverflowPolicy: OverflowDropOldest,
+	})
+
+	m1 := message{msg: Track{UserId: "A", Event: "second"}}
+
+	if err := c.queue(context.Background(), m1); err != nil {
+		t.Error("queuing a message should have dropped the oldest one:", err)
+	}
+
+	if m := <-dropped; !reflect.DeepEqual(m, m0.msg) {
+		t.Error("invalid message dropped:", m)
+	}
+
+	if err := <-errchan; err != ErrQueueFull {
+		t.Error("invalid error reported for the dropped message:", err)
+	}
+
+	if m := <-c.msgs; !reflect.DeepEqual(m.msg, m1.msg) {
+		t.Error("the newest message should have been kept in the queue:", m.msg)
+	}
+}
+
+func TestOverflowBlockWithTimeout(t *testing.T) {
+	c, _ := newFullQueueClient(Config{
+		Logger:         testLogger{t.Logf, t.Logf},
+		OverflowPolicy: OverflowBlockWithTimeout,
+		EnqueueTimeout: 10 * time.Millisecond,
+	})
+
+	t0 := time.Now()
+
+	if err := c.queue(context.Background(), message{msg: Track{UserId: "A", Event: "second"}}); err != ErrQueueFull {
+		t.Error("invalid error returned when the enqueue timeout expired:", err)
+	}
+
+	if d := time.Now().Sub(t0); d < 10*time.Millisecond {
+		t.Error("queuing the message should have waited for the enqueue timeout:", d)
+	}
+}
+
+func TestOverflowBlockContext(t *testing.T) {
+	c, _ := newFullQueueClient(Config{
+		Logger: testLogger{t.Logf, t.Logf},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := c.queue(ctx, message{msg: Track{UserId: "A", Event: "second"}}); err != context.DeadlineExceeded {
+		t.Error("invalid error returned when the context expired:", err)
+	}
+}
@@ -0,0 +1,100 @@
+package analytics
+
+import "context"
+
+type identityKey struct{}
+
+type analyticsContextKey struct{}
+
+// This type carries the identity set on a context.Context by `WithIdentity`.
+type identity struct {
+	userId      string
+	anonymousId string
+}
+
+// WithIdentity returns a copy of ctx which carries the user id and anonymous id
+// passed as arguments. Messages queued with `EnqueueContext` and the returned
+// context get these ids in place of the ones they don't have.
+func WithIdentity(ctx context.Context, userId string, anonymousId string) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity{
+		userId:      userId,
+		anonymousId: anonymousId,
+	})
+}
+
+// IdentityFromContext returns the user id and anonymous id carried by ctx, they
+// are empty if the context was not created by `WithIdentity`.
+func IdentityFromContext(ctx context.Context) (userId string, anonymousId string) {
+	id, _ := ctx.Value(identityKey{}).(identity)
+	return id.userId, id.anonymousId
+}
+
+// WithAnalyticsContext returns a copy of ctx which carries the analytics
+// context c. Messages queued with `EnqueueContext` and the returned context get
+// c merged into their own context.
+//
+// If ctx already carries an analytics context the two are merged, the fields
+// set on c take precedence.
+func WithAnalyticsContext(ctx context.Context, c *Context) context.Context {
+	if parent := AnalyticsContextFromContext(ctx); parent != nil {
+		c = mergeContext(c, parent)
+	}
+	return context.WithValue(ctx, analyticsContextKey{}, c)
+}
+
+// AnalyticsContextFromContext returns the analytics context carried by ctx, or
+// nil if there is none.
+func AnalyticsContextFromContext(ctx context.Context) *Context {
+	c, _ := ctx.Value(analyticsContextKey{}).(*Context)
+	return c
+}
+
+// Enrich returns a copy of msg with the identity and analytics context carried
+// by ctx, this is what `EnqueueContext` does before queuing a message.
+//
+// The user id and anonymous id are only set if the message doesn't have them
+// already, and the analytics context is merged with `MergeContext` so the
+// fields already set on the message take precedence. Alias messages don't get
+// the identity since their user id is the new identity of the user.
+func Enrich(ctx context.Context, msg Message) Message {
+	userId, anonymousId := IdentityFromContext(ctx)
+
+	if len(userId) != 0 || len(anonymousId) != 0 {
+		msg = setIdentity(msg, userId, anonymousId)
+	}
+
+	return MergeContext(msg, AnalyticsContextFromContext(ctx))
+}
+
+func setIdentity(msg Message, userId string, anonymousId string) Message {
+	switch m := dereferenceMessage(msg).(type) {
+	case Group:
+		setDefault(&m.UserId, userId)
+		setDefault(&m.AnonymousId, anonymousId)
+		return m
+	case Identify:
+		setDefault(&m.UserId, userId)
+		setDefault(&m.AnonymousId, anonymousId)
+		return m
+	case Page:
+		setDefault(&m.UserId, userId)
+		setDefault(&m.AnonymousId, anonymousId)
+		return m
+	case Screen:
+		setDefault(&m.UserId, userId)
+		setDefault(&m.AnonymousId, anonymousId)
+		return m
+	case Track:
+		setDefault(&m.UserId, userId)
+		setDefault(&m.AnonymousId, anonymousId)
+		return m
+	}
+
+	return msg
+}
+
+func setDefault(s *string, def string) {
+	if len(*s) == 0 {
+		*s = def
+	}
+}
@@ -0,0 +1,161 @@
+package analytics
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIdentityFromContext(t *testing.T) {
+	if userId, anonymousId := IdentityFromContext(context.Background()); userId != "" || anonymousId != "" {
+		t.Error("unexpected identity found in an empty context:", userId, anonymousId)
+	}
+
+	ctx := WithIdentity(context.Background(), "1", "A")
+
+	if userId, anonymousId := IdentityFromContext(ctx); userId != "1" || anonymousId != "A" {
+		t.Error("invalid identity found in the context:", userId, anonymousId)
+	}
+}
+
+func TestWithAnalyticsContextMerge(t *testing.T) {
+	ctx := WithAnalyticsContext(context.Background(), &Context{
+		UserAgent: "test",
+		Locale:    "en-US",
+	})
+	ctx = WithAnalyticsContext(ctx, &Context{
+		Locale: "fr-FR",
+	})
+
+	if c := AnalyticsContextFromContext(ctx); !reflect.DeepEqual(c, &Context{UserAgent: "test", Locale: "fr-FR"}) {
+		t.Errorf("invalid analytics context: %#v", c)
+	}
+
+	if c := AnalyticsContextFromContext(context.Background()); c != nil {
+		t.Errorf("unexpected analytics context found in an empty context: %#v", c)
+	}
+}
+
+func TestEnrichDoesNotShareContext(t *testing.T) {
+	ctx := WithAnalyticsContext(context.Background(), &Context{
+		IP:     net.ParseIP("10.0.0.1"),
+		Traits: Traits{"name": "A"},
+		Extra:  map[string]interface{}{"tenant": "acme"},
+	})
+	ctx = WithAnalyticsContext(ctx, &Context{Locale: "en-US"})
+
+	ref := &Context{
+		IP:     net.ParseIP("10.0.0.1"),
+		Locale: "en-US",
+		Traits: Traits{"name": "A"},
+		Extra:  map[string]interface{}{"tenant": "acme"},
+	}
+
+	msg := Enrich(ctx, Track{UserId: "1", Event: "A"}).(Track)
+
+	// Middleware may modify the merged context in place, this must not change
+	// the context carried by ctx and used by other messages.
+	delete(msg.Context.Extra, "tenant")
+	msg.Context.Traits["name"] = "B"
+	msg.Context.IP[0] = 192
+
+	if c := AnalyticsContextFromContext(ctx); !reflect.DeepEqual(c, ref) {
+		t.Errorf("the analytics context was modified: %#v", c)
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	ctx := WithIdentity(context.Background(), "1", "A")
+	ctx = WithAnalyticsContext(ctx, &Context{
+		IP:     net.ParseIP("10.0.0.1"),
+		Locale: "en-US",
+	})
+
+	tests := []struct {
+		msg Message
+		ref Message
+	}{
+		{
+			msg: Track{Event: "Download"},
+			ref: Track{UserId: "1", AnonymousId: "A", Event: "Download", Context: &Context{
+				IP:     net.ParseIP("10.0.0.1"),
+				Locale: "en-US",
+			}},
+		},
+		{
+			msg: &Page{UserId: "2", Context: &Context{Locale: "fr-FR"}},
+			ref: Page{UserId: "2", AnonymousId: "A", Context: &Context{
+				IP:     net.ParseIP("10.0.0.1"),
+				Locale: "fr-FR",
+			}},
+		},
+		{
+			msg: Alias{PreviousId: "A", UserId: "2"},
+			ref: Alias{PreviousId: "A", UserId: "2", Context: &Context{
+				IP:     net.ParseIP("10.0.0.1"),
+				Locale: "en-US",
+			}},
+		},
+	}
+
+	for _, test := range tests {
+		if msg := Enrich(ctx, test.msg); !reflect.DeepEqual(msg, test.ref) {
+			t.Errorf("invalid enriched message:\n- expected %#v\n- found: %#v", test.ref, msg)
+		}
+	}
+
+	if msg := Enrich(context.Background(), Track{Event: "Download"}); !reflect.DeepEqual(msg, Track{Event: "Download"}) {
+		t.Errorf("the message should not be modified without identity or analytics context: %#v", msg)
+	}
+}
+
+func TestClientEnqueueContextEnrich(t *testing.T) {
+	msgs := make(chan Message, 1)
+
+	client, _ := NewWithConfig("h97jamjwbh", Config{
+		Logger: testLogger{t.Logf, t.Logf},
+		Middleware: []Middleware{
+			func(msg Message) (Message, error) {
+				msgs <- msg
+				return nil, nil
+			},
+		},
+		now: mockTime,
+		uid: mockId,
+	})
+	defer client.Close()
+
+	ctx := WithIdentity(context.Background(), "1", "")
+	ctx = WithAnalyticsContext(ctx, &Context{UserAgent: "test"})
+
+	if err := client.EnqueueContext(ctx, Track{Event: "Download"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if msg := <-msgs; !reflect.DeepEqual(msg, Track{
+		Type:      "track",
+		MessageId: "I'm unique",
+		UserId:    "1",
+		Event:     "Download",
+		Timestamp: mockTime(),
+		Context:   &Context{UserAgent: "test"},
+	}) {
+		t.Errorf("invalid message passed to the middleware: %#v", msg)
+	}
+}
+
+func TestClientEnqueueContextCanceled(t *testing.T) {
+	c, _ := newFullQueueClient(Config{
+		Logger: testLogger{t.Logf, t.Logf},
+	})
+
+	ctx := WithIdentity(context.Background(), "1", "")
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	if err := c.EnqueueContext(ctx, Track{Event: "second"}); err != context.DeadlineExceeded {
+		t.Error("invalid error returned when the context expired:", err)
+	}
+}
@@ -1,5 +1,10 @@
 package analytics
 
+import (
+	"encoding/json"
+	"reflect"
+)
+
 // This type is used to represent properties in messages that support it.
 // It is a free-form object so the application can set any value it sees fit but
 // a few helper method are defined to make it easier to instantiate properties with
@@ -99,6 +104,10 @@ func (p Properties) SetProducts(products ...Product) Properties {
 	return p.Set("products", products)
 }
 
+func (p Properties) SetLegacyProducts(products ...LegacyProduct) Properties {
+	return p.Set("products", products)
+}
+
 func (p Properties) SetRepeat(repeat bool) Properties {
 	return p.Set("repeat", repeat)
 }
@@ -108,10 +117,68 @@ func (p Properties) Set(name string, value interface{}) Properties {
 	return p
 }
 
-// This type represents products in the E-commerce API.
+// This type represents products in the E-commerce API as described in
+// https://segment.com/docs/connections/spec/ecommerce/v2/#product-viewed
 type Product struct {
-	ID    string  `json:"id,omitempty"`
-	SKU   string  `json:"sky,omitempty"`
-	Name  string  `json:"name,omitempty"`
-	Price float64 `json:"price"`
+	ID       string  `json:"product_id,omitempty"`
+	SKU      string  `json:"sku,omitempty"`
+	Category string  `json:"category,omitempty"`
+	Name     string  `json:"name,omitempty"`
+	Brand    string  `json:"brand,omitempty"`
+	Variant  string  `json:"variant,omitempty"`
+	Price    float64 `json:"price,omitempty"`
+	Quantity int     `json:"quantity,omitempty"`
+	Coupon   string  `json:"coupon,omitempty"`
+	Position int     `json:"position,omitempty"`
+	URL      string  `json:"url,omitempty"`
+	ImageURL string  `json:"image_url,omitempty"`
+
+	// This map is used to allow extensions to the product specifications, like
+	// custom product properties.
+	// The fields of this map are inlined in the serialized product object,
+	// there is no actual "extra" field in the JSON representation.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// Satisfies the json.Marshaler interface.
+func (p Product) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.specProperties())
+}
+
+// This type represents products serialized with the JSON keys used by earlier
+// versions of this package, for programs that depend on them: the product id
+// and SKU are written to the "id" and "sky" keys, and the price is always
+// written even when it is zero. The other fields use the keys defined by the
+// spec.
+type LegacyProduct Product
+
+// Satisfies the json.Marshaler interface.
+func (p LegacyProduct) MarshalJSON() ([]byte, error) {
+	m := Product(p).specProperties()
+
+	delete(m, "product_id")
+	delete(m, "sku")
+
+	if len(p.ID) != 0 {
+		m["id"] = p.ID
+	}
+
+	if len(p.SKU) != 0 {
+		m["sky"] = p.SKU
+	}
+
+	m["price"] = p.Price
+	return json.Marshal(m)
+}
+
+// Returns the properties of a product with the names defined by the spec, the
+// fields of the product take precedence over the values of the `Extra` map.
+func (p Product) specProperties() Properties {
+	m := make(Properties, 12+len(p.Extra))
+
+	for name, value := range p.Extra {
+		m[name] = value
+	}
+
+	return structToMap(reflect.ValueOf(p), m)
 }
@@ -1,6 +1,7 @@
 package analytics
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
 )
@@ -69,3 +70,69 @@ func TestPropertiesMulti(t *testing.T) {
 	}
 
 }
+
+func TestProductMarshalJSON(t *testing.T) {
+	product := Product{
+		ID:       "507f1f77bcf86cd799439011",
+		SKU:      "G-32",
+		Category: "Games",
+		Name:     "Monopoly: 3rd Edition",
+		Brand:    "Hasbro",
+		Variant:  "200 pieces",
+		Price:    18.99,
+		Quantity: 1,
+		Coupon:   "MAYDEALS",
+		Position: 3,
+		URL:      "https://www.example.com/product/path",
+		ImageURL: "https://www.example.com/product/path.jpg",
+		Extra: map[string]interface{}{
+			"color": "red",
+			"sku":   "overwritten",
+		},
+	}
+
+	const ref = `{"brand":"Hasbro","category":"Games","color":"red","coupon":"MAYDEALS","image_url":"https://www.example.com/product/path.jpg","name":"Monopoly: 3rd Edition","position":3,"price":18.99,"product_id":"507f1f77bcf86cd799439011","quantity":1,"sku":"G-32","url":"https://www.example.com/product/path","variant":"200 pieces"}`
+
+	if b, err := json.Marshal(product); err != nil {
+		t.Error(err)
+	} else if s := string(b); s != ref {
+		t.Errorf("invalid product serialization:\n- expected %s\n- found: %s", ref, s)
+	}
+
+	if b, _ := json.Marshal(Product{Name: "A"}); string(b) != `{"name":"A"}` {
+		t.Error("empty product fields must be omitted:", string(b))
+	}
+}
+
+func TestLegacyProductMarshalJSON(t *testing.T) {
+	tests := []struct {
+		product LegacyProduct
+		ref     string
+	}{
+		{LegacyProduct{ID: "1", SKU: "G-32", Name: "A", Price: 42}, `{"id":"1","name":"A","price":42,"sky":"G-32"}`},
+		{LegacyProduct{ID: "1", Brand: "Hasbro"}, `{"brand":"Hasbro","id":"1","price":0}`},
+	}
+
+	for _, test := range tests {
+		if b, err := json.Marshal(test.product); err != nil {
+			t.Error(err)
+		} else if s := string(b); s != test.ref {
+			t.Errorf("invalid legacy product serialization:\n- expected %s\n- found: %s", test.ref, s)
+		}
+	}
+}
+
+func TestSetLegacyProducts(t *testing.T) {
+	p := NewProperties().
+		SetLegacyProducts(LegacyProduct{ID: "1"}).
+		Set("other", []Product{{ID: "2"}})
+
+	const ref = `{"other":[{"product_id":"2"}],"products":[{"id":"1","price":0}]}`
+
+	// Legacy products don't change how other products are serialized.
+	if b, err := json.Marshal(p); err != nil {
+		t.Error(err)
+	} else if s := string(b); s != ref {
+		t.Errorf("invalid properties serialization:\n- expected %s\n- found: %s", ref, s)
+	}
+}
@@ -0,0 +1,92 @@
+package analytics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// This function is the retry policy used by clients when none was set in the
+// configuration.
+// Requests that failed with a response from the API are only retried if the
+// status code indicates a temporary condition (429 or 5xx), all other errors,
+// like network errors, are considered temporary.
+func DefaultRetryPolicy(err error) bool {
+	if e, ok := err.(HTTPError); ok {
+		return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+	}
+	return true
+}
+
+// This constant sets the maximum time that clients pause uploads for when the
+// API responds with a `Retry-After` header, longer delays are capped so a
+// malformed header can't stop a client from sending messages.
+const MaxRetryAfter = 5 * time.Minute
+
+// Returns how long the API asked the client to wait before sending another
+// request, based on the `Retry-After` header of the response that caused the
+// error passed as argument.
+// The header may either be a number of seconds or an HTTP date, the second
+// return value is false if the error didn't carry a valid header. The delay is
+// capped to `MaxRetryAfter`.
+func retryAfter(err error, now time.Time) (d time.Duration, ok bool) {
+	e, isHTTP := err.(HTTPError)
+	if !isHTTP {
+		return
+	}
+
+	h := strings.TrimSpace(e.Header.Get("Retry-After"))
+	if len(h) == 0 {
+		return
+	}
+
+	if secs, err := strconv.ParseInt(h, 10, 64); err == nil {
+		if secs < 0 {
+			return
+		}
+		// The number of seconds is compared before being converted to avoid
+		// overflowing the duration.
+		if secs > int64(MaxRetryAfter/time.Second) {
+			return MaxRetryAfter, true
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(h); err == nil {
+		if d = t.Sub(now); d < 0 {
+			d = 0
+		} else if d > MaxRetryAfter {
+			d = MaxRetryAfter
+		}
+		return d, true
+	}
+
+	return
+}
+
+// This type is used to pause the uploads of all the goroutines of a client until
+// a point in time.
+type pause struct {
+	mutex sync.Mutex
+	until time.Time
+}
+
+// Pauses uploads until the given time, unless they were already paused for
+// longer.
+func (p *pause) extend(until time.Time) {
+	p.mutex.Lock()
+	if until.After(p.until) {
+		p.until = until
+	}
+	p.mutex.Unlock()
+}
+
+// Returns how long uploads are still paused for, the value is zero or negative
+// when uploads are not paused.
+func (p *pause) remaining(now time.Time) time.Duration {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.until.Sub(now)
+}
@@ -0,0 +1,75 @@
+package analytics
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDefaultRetryPolicy(t *testing.T) {
+	tests := map[string]struct {
+		err   error
+		retry bool
+	}{
+		"network": {testError, true},
+		"400":     {HTTPError{StatusCode: http.StatusBadRequest}, false},
+		"401":     {HTTPError{StatusCode: http.StatusUnauthorized}, false},
+		"413":     {HTTPError{StatusCode: http.StatusRequestEntityTooLarge}, false},
+		"429":     {HTTPError{StatusCode: http.StatusTooManyRequests}, true},
+		"500":     {HTTPError{StatusCode: http.StatusInternalServerError}, true},
+		"503":     {HTTPError{StatusCode: http.StatusServiceUnavailable}, true},
+	}
+
+	for name, test := range tests {
+		if retry := DefaultRetryPolicy(test.err); retry != test.retry {
+			t.Errorf("%s: invalid retry decision: %t", name, retry)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := mockTime()
+
+	header := func(v string) http.Header {
+		return http.Header{"Retry-After": {v}}
+	}
+
+	tests := map[string]struct {
+		err error
+		d   time.Duration
+		ok  bool
+	}{
+		"network":   {testError, 0, false},
+		"no header": {HTTPError{StatusCode: 429}, 0, false},
+		"seconds":   {HTTPError{StatusCode: 429, Header: header("120")}, 2 * time.Minute, true},
+		"negative":  {HTTPError{StatusCode: 429, Header: header("-1")}, 0, false},
+		"date":      {HTTPError{StatusCode: 503, Header: header(now.Add(time.Minute).Format(http.TimeFormat))}, time.Minute, true},
+		"past date": {HTTPError{StatusCode: 503, Header: header(now.Add(-time.Minute).Format(http.TimeFormat))}, 0, true},
+		"malformed": {HTTPError{StatusCode: 429, Header: header("soon")}, 0, false},
+		"too long":  {HTTPError{StatusCode: 429, Header: header("3600")}, MaxRetryAfter, true},
+		"overflow":  {HTTPError{StatusCode: 429, Header: header("9223372036854775807")}, MaxRetryAfter, true},
+		"far date":  {HTTPError{StatusCode: 503, Header: header(now.Add(24 * time.Hour).Format(http.TimeFormat))}, MaxRetryAfter, true},
+	}
+
+	for name, test := range tests {
+		if d, ok := retryAfter(test.err, now); d != test.d || ok != test.ok {
+			t.Errorf("%s: invalid retry delay: %s (%t)", name, d, ok)
+		}
+	}
+}
+
+func TestPause(t *testing.T) {
+	var p pause
+	now := mockTime()
+
+	if d := p.remaining(now); d > 0 {
+		t.Error("uploads should not be paused by default:", d)
+	}
+
+	p.extend(now.Add(time.Minute))
+	p.extend(now.Add(time.Second))
+
+	if d := p.remaining(now); d != time.Minute {
+		t.Error("a shorter pause should not override a longer one:", d)
+	}
+}
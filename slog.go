@@ -0,0 +1,51 @@
+//go:build go1.21
+// +build go1.21
+
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// This function instantiates an object that satisfies the
+// analytics.StructuredLogger interface and sends logs to the slog logger passed
+// as argument.
+//
+// Log messages are sent with their fields as typed attributes, and log levels
+// map to the levels of the same name in the slog package. The handler of the
+// slog logger may filter messages further than the `LogLevel` of the client.
+func SlogLogger(logger *slog.Logger) StructuredLogger {
+	return slogLogger{
+		logger: logger,
+	}
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func (l slogLogger) Logf(format string, args ...interface{}) {
+	l.logger.Info(fmt.Sprintf(format, args...))
+}
+
+func (l slogLogger) Errorf(format string, args ...interface{}) {
+	l.logger.Error(fmt.Sprintf(format, args...))
+}
+
+func (l slogLogger) Log(level LogLevel, msg string, fields ...Field) {
+	attrs := make([]slog.Attr, len(fields))
+
+	for i, f := range fields {
+		if err, ok := f.Value.(error); ok {
+			// Errors are logged as strings, most handlers would otherwise
+			// serialize them as empty objects.
+			attrs[i] = slog.String(f.Key, err.Error())
+		} else {
+			attrs[i] = slog.Any(f.Key, f.Value)
+		}
+	}
+
+	l.logger.LogAttrs(context.Background(), slog.Level(level), msg, attrs...)
+}
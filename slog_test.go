@@ -0,0 +1,51 @@
+//go:build go1.21
+// +build go1.21
+
+package analytics
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buffer bytes.Buffer
+	var logger = SlogLogger(slog.New(slog.NewJSONHandler(&buffer, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})))
+
+	logger.Log(LevelDebug, "response received", Field{"batchId", "A"}, Field{"status", 200})
+	logger.Log(LevelError, "sending request failed", Field{"attempt", 1}, Field{"error", errors.New("timeout")})
+	logger.Errorf("%s", "something went wrong!")
+
+	var logs []map[string]interface{}
+	dec := json.NewDecoder(&buffer)
+
+	for dec.More() {
+		var m map[string]interface{}
+		if err := dec.Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+		logs = append(logs, m)
+	}
+
+	ref := []map[string]interface{}{
+		{"level": "DEBUG", "msg": "response received", "batchId": "A", "status": 200.0},
+		{"level": "ERROR", "msg": "sending request failed", "attempt": 1.0, "error": "timeout"},
+		{"level": "ERROR", "msg": "something went wrong!"},
+	}
+
+	if !reflect.DeepEqual(logs, ref) {
+		t.Errorf("invalid logs from slog logger:\n- expected: %v\n- found: %v", ref, logs)
+	}
+}
@@ -0,0 +1,90 @@
+package analytics
+
+// Values implementing this interface are events defined by one of the Segment
+// specs, which can be converted to track messages with `SpecTrack`.
+//
+// Spec event types validate the fields required by the spec and set the event
+// name and properties with the names the spec defines, so applications don't
+// have to spell them out.
+type SpecEvent interface {
+
+	// Returns an error if a field required by the spec is missing.
+	Validate() error
+
+	// Sets the event name, properties and context fields of the track message
+	// passed as argument. The method is only called on valid events, the
+	// properties and context of the message are never nil and are safe to
+	// modify.
+	Apply(msg *Track)
+}
+
+// Converts the spec event passed as second argument to a track message.
+//
+// The base track message carries the fields which are not part of the event,
+// like the user id or the timestamp. Its properties and context are copied
+// before the event is applied, and the properties set by the event take
+// precedence over the base properties of the same name.
+//
+// Here's a quick example of how this function is meant to be used:
+//
+//	track, err := analytics.SpecTrack(analytics.Track{UserId: "0123456789"}, analytics.OrderCompleted{
+//		OrderId:  "50314b8e9bcf000000000000",
+//		Total:    27.5,
+//		Currency: "USD",
+//		Products: []analytics.Product{{ID: "507f1f77bcf86cd799439011", Price: 19}},
+//	})
+func SpecTrack(base Track, event SpecEvent) (Track, error) {
+	if err := event.Validate(); err != nil {
+		return Track{}, err
+	}
+
+	properties := make(Properties, len(base.Properties)+10)
+
+	for k, v := range base.Properties {
+		properties[k] = v
+	}
+
+	context := &Context{}
+
+	if base.Context != nil {
+		*context = *base.Context
+	}
+
+	base.Properties = properties
+	base.Context = context
+
+	event.Apply(&base)
+	return base, nil
+}
+
+// The following methods set properties only when the value is not the zero
+// value of its type, spec properties are all optional unless validated by the
+// event.
+
+func (p Properties) setString(name string, value string) Properties {
+	if len(value) != 0 {
+		p[name] = value
+	}
+	return p
+}
+
+func (p Properties) setFloat(name string, value float64) Properties {
+	if value != 0 {
+		p[name] = value
+	}
+	return p
+}
+
+func (p Properties) setInt(name string, value int) Properties {
+	if value != 0 {
+		p[name] = value
+	}
+	return p
+}
+
+func (p Properties) setBool(name string, value bool) Properties {
+	if value {
+		p[name] = value
+	}
+	return p
+}
@@ -0,0 +1,55 @@
+package analytics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSpecTrack(t *testing.T) {
+	base := Track{
+		UserId:     "0123456789",
+		Context:    &Context{Locale: "en-US"},
+		Properties: Properties{"plan": "pro", "order_id": "overwritten"},
+	}
+
+	track, err := SpecTrack(base, OrderCompleted{OrderId: "A", Total: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref := Track{
+		UserId:  "0123456789",
+		Event:   "Order Completed",
+		Context: &Context{Locale: "en-US"},
+		Properties: Properties{
+			"plan":     "pro",
+			"order_id": "A",
+			"total":    10.0,
+		},
+	}
+
+	if !reflect.DeepEqual(track, ref) {
+		t.Errorf("invalid track message:\n- expected %#v\n- found: %#v", ref, track)
+	}
+
+	if base.Properties["order_id"] != "overwritten" || track.Context == base.Context {
+		t.Error("the properties and context of the base message must not be modified")
+	}
+}
+
+func TestSpecTrackNilBase(t *testing.T) {
+	track, err := SpecTrack(Track{UserId: "0123456789"}, ProductsSearched{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if track.Properties == nil || track.Context == nil {
+		t.Error("the properties and context of the message must be set")
+	}
+}
+
+func TestSpecTrackInvalid(t *testing.T) {
+	if _, err := SpecTrack(Track{UserId: "0123456789"}, OrderCompleted{}); err == nil {
+		t.Error("no error returned when converting an invalid spec event")
+	}
+}
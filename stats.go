@@ -0,0 +1,73 @@
+package analytics
+
+// This type represents a snapshot of the statistics maintained by a client, it
+// is returned by the `Stats` method of clients.
+//
+// Counters are cumulative since the client was created, so applications can
+// compute rates by comparing snapshots taken at different times.
+type Stats struct {
+
+	// The number of messages accepted by `Enqueue`.
+	Enqueued int64
+
+	// The number of messages dropped by middleware or because the client queue
+	// was full.
+	Dropped int64
+
+	// The number of messages successfully sent to the API.
+	Delivered int64
+
+	// The number of messages that failed to be sent to the API.
+	Failed int64
+
+	// The number of messages that were given up on because the client was
+	// closed before they could be sent.
+	Abandoned int64
+
+	// The number of times sending a batch was retried after a failure.
+	Retries int64
+
+	// The number of batches that were not sent because the maximum number of
+	// concurrent requests was reached (see `ErrTooManyRequests`).
+	Rejected int64
+
+	// The number of messages that were queued but not sent in a batch yet.
+	QueueDepth int64
+
+	// The number of batches being sent to the API.
+	InFlight int64
+
+	// The distribution of the number of messages per batch.
+	BatchMessages Histogram
+
+	// The distribution of the size of batches in bytes, before compression.
+	BatchBytes Histogram
+
+	// The distribution of the duration of upload requests in seconds,
+	// including the ones that failed.
+	UploadLatency Histogram
+
+	// The distribution of the size of serialized messages in bytes.
+	MessageBytes Histogram
+
+	// The number of responses received from the API, indexed by HTTP status
+	// code. Uploads that failed before receiving a response are not counted.
+	StatusCodes map[int]int64
+}
+
+// This type represents the distribution of values observed by a client.
+type Histogram struct {
+
+	// The upper bounds of the histogram buckets, in increasing order.
+	Buckets []float64
+
+	// The number of values lower or equal to the upper bound of each bucket,
+	// values greater than the last bucket are only part of `Count`.
+	Counts []uint64
+
+	// The total number of values observed.
+	Count uint64
+
+	// The sum of all values observed.
+	Sum float64
+}
@@ -0,0 +1,475 @@
+package analytics
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Values implementing this interface are used by analytics clients to persist
+// messages before they are sent, so they survive crashes of the application.
+//
+// Storage methods may be called concurrently from multiple goroutines.
+type Storage interface {
+
+	// Persists the serialized message passed as argument and returns a
+	// sequence number that identifies it in the storage.
+	Append(msg []byte) (uint64, error)
+
+	// Marks the messages identified by the sequence numbers passed as
+	// arguments as delivered, they will not be returned by a later call to
+	// `Replay`.
+	Ack(seqs ...uint64) error
+
+	// Returns the messages that were persisted before the storage was opened
+	// and were never acknowledged, ordered by sequence number.
+	Replay() ([]StoredMessage, error)
+
+	// Releases the resources held by the storage.
+	Close() error
+}
+
+// This type represents a message returned by the `Replay` method of a storage.
+type StoredMessage struct {
+	Seq  uint64
+	Data []byte
+}
+
+// Instances of this type carry the different configuration options that may
+// be set when opening a disk storage.
+//
+// Each field's zero-value is either meaningful or interpreted as using the
+// default value defined by the library.
+type DiskStorageConfig struct {
+
+	// The size in bytes after which the storage stops appending to the current
+	// segment file and creates a new one, set to `DefaultSegmentSize` by
+	// default.
+	// Segment files are removed once all the messages they contain have been
+	// acknowledged.
+	SegmentSize int64
+
+	// When set to true the storage syncs segment files to disk after each write
+	// instead of relying on the operating system to flush its buffers. This
+	// protects messages against power failures at the cost of throughput.
+	Sync bool
+}
+
+// This constant sets the default segment size used by disk storages if none
+// was explicitly set.
+const DefaultSegmentSize = 16 * 1024 * 1024
+
+// Opens a storage that writes messages to an append-only, segmented log in the
+// directory passed as argument, the directory is created if it didn't exist.
+// The storage is opened with the default configuration.
+func NewDiskStorage(dir string) (Storage, error) {
+	return NewDiskStorageWithConfig(dir, DiskStorageConfig{})
+}
+
+// Opens a storage that writes messages to an append-only, segmented log in the
+// directory passed as argument, using the configuration passed as second
+// argument.
+//
+// Records left incomplete by a crash are detected with a checksum and discarded
+// when the storage is opened.
+func NewDiskStorageWithConfig(dir string, config DiskStorageConfig) (Storage, error) {
+	if config.SegmentSize < 0 {
+		return nil, fmt.Errorf("analytics.NewDiskStorage: negative segment sizes are not supported: %d", config.SegmentSize)
+	}
+
+	if config.SegmentSize == 0 {
+		config.SegmentSize = DefaultSegmentSize
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	s := &diskStorage{
+		dir:    dir,
+		config: config,
+		next:   1,
+	}
+
+	if err := s.open(); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+var (
+	// This error is returned by storage methods called after the storage was
+	// closed.
+	errStorageClosed = errors.New("the storage was already closed")
+
+	// This error is used internally to report records that were only partially
+	// written or got corrupted on disk.
+	errCorruptedRecord = errors.New("corrupted storage record")
+)
+
+const (
+	segmentExt = ".wal"
+
+	recordAppend byte = 1
+	recordAck    byte = 2
+
+	// A record header is made of a CRC32 checksum, the payload length, the
+	// record kind and the sequence number, the checksum covers everything that
+	// comes after it in the record.
+	recordHeaderSize = 4 + 4 + 1 + 8
+
+	// Records are never larger than a batch, a length above this limit can only
+	// come from a corrupted header.
+	maxRecordBytes = maxBatchBytes
+)
+
+type diskStorage struct {
+	dir    string
+	config DiskStorageConfig
+
+	mutex    sync.Mutex
+	file     *os.File
+	size     int64
+	next     uint64
+	segments []*segment
+	replay   []StoredMessage
+	closed   bool
+}
+
+// Segments track which of the messages they contain are still waiting to be
+// acknowledged, a segment file can be removed once this set is empty.
+type segment struct {
+	path    string
+	first   uint64
+	pending map[uint64]struct{}
+}
+
+func (s *diskStorage) Append(msg []byte) (seq uint64, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		err = errStorageClosed
+		return
+	}
+
+	if s.size >= s.config.SegmentSize {
+		if err = s.rotate(); err != nil {
+			return
+		}
+	}
+
+	seq = s.next
+
+	if err = s.write(appendRecord(nil, recordAppend, seq, msg)); err != nil {
+		return
+	}
+
+	s.next++
+	s.segments[len(s.segments)-1].pending[seq] = struct{}{}
+	return
+}
+
+func (s *diskStorage) Ack(seqs ...uint64) (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return errStorageClosed
+	}
+
+	b := make([]byte, 0, len(seqs)*recordHeaderSize)
+
+	for _, seq := range seqs {
+		b = appendRecord(b, recordAck, seq, nil)
+	}
+
+	if err = s.write(b); err != nil {
+		return
+	}
+
+	for _, seq := range seqs {
+		if seg := s.lookup(seq); seg != nil {
+			delete(seg.pending, seq)
+		}
+	}
+
+	return s.compact()
+}
+
+func (s *diskStorage) Replay() (msgs []StoredMessage, err error) {
+	s.mutex.Lock()
+	msgs, s.replay = s.replay, nil
+	s.mutex.Unlock()
+	return
+}
+
+func (s *diskStorage) Close() (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return errStorageClosed
+	}
+
+	s.closed = true
+
+	if s.file != nil {
+		err = s.file.Close()
+	}
+
+	return
+}
+
+// Loads the segments found in the storage directory, reconstructs the set of
+// unacknowledged messages and opens the last segment for writing.
+func (s *diskStorage) open() error {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	pending := make(map[uint64][]byte)
+
+	// The names of segment files are zero-padded sequence numbers and ReadDir
+	// returns files sorted by name, so segments are loaded in the order they
+	// were written.
+	for _, f := range files {
+		name := f.Name()
+
+		if f.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+
+		first, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		seg := &segment{
+			path:    filepath.Join(s.dir, name),
+			first:   first,
+			pending: make(map[uint64]struct{}),
+		}
+
+		if err := s.load(seg, pending); err != nil {
+			return err
+		}
+
+		s.segments = append(s.segments, seg)
+	}
+
+	// Acknowledgements may be written to a later segment than the one holding
+	// the message, so the pending sets can only be built once all segments
+	// have been loaded.
+	for seq, data := range pending {
+		if seg := s.lookup(seq); seg != nil {
+			seg.pending[seq] = struct{}{}
+		}
+
+		s.replay = append(s.replay, StoredMessage{
+			Seq:  seq,
+			Data: data,
+		})
+	}
+
+	sort.Slice(s.replay, func(i, j int) bool {
+		return s.replay[i].Seq < s.replay[j].Seq
+	})
+
+	if len(s.segments) == 0 {
+		return s.rotate()
+	}
+
+	if err := s.compact(); err != nil {
+		return err
+	}
+
+	last := s.segments[len(s.segments)-1]
+
+	if s.file, err = os.OpenFile(last.path, os.O_WRONLY|os.O_APPEND, 0644); err != nil {
+		return err
+	}
+
+	info, err := s.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	s.size = info.Size()
+	return nil
+}
+
+// Reads the records of a segment file, adding the messages it contains to the
+// pending map and removing the ones it acknowledges.
+// Reading stops at the first incomplete or corrupted record, which can only be
+// the result of an interrupted write, and the file is truncated there so new
+// records are not appended after garbage.
+func (s *diskStorage) load(seg *segment, pending map[uint64][]byte) error {
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	off := int64(0)
+
+	for {
+		kind, seq, data, n, err := readRecord(r)
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return os.Truncate(seg.path, off)
+		}
+
+		switch kind {
+		case recordAppend:
+			pending[seq] = data
+		case recordAck:
+			delete(pending, seq)
+		}
+
+		if seq >= s.next {
+			s.next = seq + 1
+		}
+
+		off += int64(n)
+	}
+}
+
+// Closes the current segment file and creates a new one that starts at the
+// next sequence number.
+func (s *diskStorage) rotate() (err error) {
+	if s.file != nil {
+		if err = s.file.Close(); err != nil {
+			return
+		}
+		s.file = nil
+	}
+
+	seg := &segment{
+		path:    filepath.Join(s.dir, fmt.Sprintf("%020d%s", s.next, segmentExt)),
+		first:   s.next,
+		pending: make(map[uint64]struct{}),
+	}
+
+	if s.file, err = os.OpenFile(seg.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644); err != nil {
+		return
+	}
+
+	s.size = 0
+	s.segments = append(s.segments, seg)
+	return s.compact()
+}
+
+// Removes the oldest segments as long as all their messages were acknowledged.
+// Segments are only removed from the head of the log because they may carry
+// acknowledgements for messages stored in the segments that came before them.
+// The last segment is never removed since it is the one being written to.
+func (s *diskStorage) compact() error {
+	for len(s.segments) > 1 && len(s.segments[0].pending) == 0 {
+		if err := os.Remove(s.segments[0].path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		s.segments = s.segments[1:]
+	}
+	return nil
+}
+
+func (s *diskStorage) write(b []byte) (err error) {
+	if _, err = s.file.Write(b); err != nil {
+		return
+	}
+
+	s.size += int64(len(b))
+
+	if s.config.Sync {
+		err = s.file.Sync()
+	}
+
+	return
+}
+
+// Returns the segment that the sequence number passed as argument was written
+// to, or nil if that segment was already removed.
+func (s *diskStorage) lookup(seq uint64) *segment {
+	i := sort.Search(len(s.segments), func(i int) bool {
+		return s.segments[i].first > seq
+	})
+
+	if i == 0 {
+		return nil
+	}
+
+	return s.segments[i-1]
+}
+
+func appendRecord(b []byte, kind byte, seq uint64, data []byte) []byte {
+	var h [recordHeaderSize]byte
+	binary.BigEndian.PutUint32(h[4:8], uint32(len(data)))
+	h[8] = kind
+	binary.BigEndian.PutUint64(h[9:], seq)
+
+	crc := crc32.NewIEEE()
+	crc.Write(h[8:])
+	crc.Write(data)
+	binary.BigEndian.PutUint32(h[:4], crc.Sum32())
+
+	b = append(b, h[:]...)
+	return append(b, data...)
+}
+
+func readRecord(r io.Reader) (kind byte, seq uint64, data []byte, n int, err error) {
+	var h [recordHeaderSize]byte
+
+	if n, err = io.ReadFull(r, h[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = errCorruptedRecord
+		}
+		return
+	}
+
+	size := binary.BigEndian.Uint32(h[4:8])
+
+	if size > maxRecordBytes {
+		err = errCorruptedRecord
+		return
+	}
+
+	data = make([]byte, size)
+	kind = h[8]
+	seq = binary.BigEndian.Uint64(h[9:])
+
+	if _, err = io.ReadFull(r, data); err != nil {
+		err = errCorruptedRecord
+		return
+	}
+
+	n += len(data)
+
+	crc := crc32.NewIEEE()
+	crc.Write(h[8:])
+	crc.Write(data)
+
+	if crc.Sum32() != binary.BigEndian.Uint32(h[:4]) {
+		err = errCorruptedRecord
+	}
+
+	return
+}
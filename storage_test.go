@@ -0,0 +1,178 @@
+package analytics
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "analytics-go-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestDiskStorageReplay(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	s, err := NewDiskStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, msg := range []string{`{"a":1}`, `{"b":2}`, `{"c":3}`} {
+		if _, err := s.Append([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.Ack(2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if s, err = NewDiskStorage(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	msgs, err := s.Replay()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref := []StoredMessage{
+		{Seq: 1, Data: []byte(`{"a":1}`)},
+		{Seq: 3, Data: []byte(`{"c":3}`)},
+	}
+
+	if !reflect.DeepEqual(msgs, ref) {
+		t.Errorf("invalid messages replayed:\n- expected %q\n- found: %q", ref, msgs)
+	}
+
+	// Sequence numbers must keep growing after the storage was reopened.
+	if seq, err := s.Append([]byte(`{"d":4}`)); err != nil {
+		t.Error(err)
+	} else if seq != 4 {
+		t.Error("invalid sequence number after reopening the storage:", seq)
+	}
+}
+
+func TestDiskStorageSegments(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	s, err := NewDiskStorageWithConfig(dir, DiskStorageConfig{
+		SegmentSize: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var seqs []uint64
+
+	for i := 0; i != 20; i++ {
+		seq, err := s.Append([]byte(`{"type":"track","event":"Download"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		seqs = append(seqs, seq)
+	}
+
+	if n := len(segmentFiles(t, dir)); n < 2 {
+		t.Fatal("the storage should have created multiple segments:", n)
+	}
+
+	if err := s.Ack(seqs...); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(segmentFiles(t, dir)); n != 1 {
+		t.Error("acknowledged segments should have been removed:", n)
+	}
+}
+
+func TestDiskStorageTornWrite(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	s, err := NewDiskStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Append([]byte(`{"a":1}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Close()
+
+	// Emulate a crash in the middle of writing a record.
+	files := segmentFiles(t, dir)
+	f, err := os.OpenFile(files[len(files)-1], os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(appendRecord(nil, recordAppend, 2, []byte(`{"b":2}`))[:recordHeaderSize+2])
+	f.Close()
+
+	if s, err = NewDiskStorage(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if seq, err := s.Append([]byte(`{"c":3}`)); err != nil {
+		t.Fatal(err)
+	} else if seq != 2 {
+		t.Error("invalid sequence number after discarding an incomplete record:", seq)
+	}
+
+	msgs, _ := s.Replay()
+	ref := []StoredMessage{{Seq: 1, Data: []byte(`{"a":1}`)}}
+
+	if !reflect.DeepEqual(msgs, ref) {
+		t.Errorf("invalid messages replayed:\n- expected %q\n- found: %q", ref, msgs)
+	}
+}
+
+func TestDiskStorageClosed(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	s, err := NewDiskStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	if _, err := s.Append([]byte(`{}`)); err != errStorageClosed {
+		t.Error("appending to a closed storage should fail:", err)
+	}
+
+	if err := s.Close(); err != errStorageClosed {
+		t.Error("closing a storage twice should fail:", err)
+	}
+}
+
+func TestDiskStorageConfigError(t *testing.T) {
+	if _, err := NewDiskStorageWithConfig("", DiskStorageConfig{SegmentSize: -1}); err == nil {
+		t.Error("no error returned when opening a storage with a negative segment size")
+	}
+}
@@ -0,0 +1,51 @@
+package analytics
+
+import (
+	"context"
+	"net/http"
+)
+
+// Values implementing this interface are used by analytics clients to trace the
+// batches they send, for example by creating spans in a distributed tracing
+// system.
+//
+// Tracer methods may be called concurrently from multiple goroutines.
+type Tracer interface {
+
+	// Called when the client starts sending a batch. The returned context is
+	// used for the upload requests of the batch, and the returned function is
+	// called with the final error (or nil) once the client stopped trying to
+	// send it.
+	StartSend(ctx context.Context, batch BatchInfo) (context.Context, func(error))
+
+	// Called before each attempt at uploading a batch, attempt starts at zero.
+	// The method may return a modified request, for example to propagate the
+	// trace context in the request headers. The returned function is called
+	// with the status code of the response (zero if none was received) and the
+	// error of the upload (or nil).
+	StartUpload(req *http.Request, batch BatchInfo, attempt int) (*http.Request, func(int, error))
+}
+
+// This type describes a batch of messages passed to tracers.
+type BatchInfo struct {
+
+	// The message id of the batch.
+	MessageId string
+
+	// The number of messages in the batch.
+	Messages int
+
+	// The size of the batch in bytes, before compression.
+	Bytes int
+}
+
+// The default tracer used by clients, which does nothing.
+type nopTracer struct{}
+
+func (nopTracer) StartSend(ctx context.Context, batch BatchInfo) (context.Context, func(error)) {
+	return ctx, func(error) {}
+}
+
+func (nopTracer) StartUpload(req *http.Request, batch BatchInfo, attempt int) (*http.Request, func(int, error)) {
+	return req, func(int, error) {}
+}
@@ -0,0 +1,106 @@
+package analytics
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testTraceKey struct{}
+
+// Instances of this type record the events they receive from clients.
+type testTracer struct {
+	mutex   sync.Mutex
+	events  []string
+	batches []BatchInfo
+	status  []int
+	err     error
+}
+
+func (t *testTracer) record(event string) {
+	t.mutex.Lock()
+	t.events = append(t.events, event)
+	t.mutex.Unlock()
+}
+
+func (t *testTracer) StartSend(ctx context.Context, batch BatchInfo) (context.Context, func(error)) {
+	t.record("send")
+	t.mutex.Lock()
+	t.batches = append(t.batches, batch)
+	t.mutex.Unlock()
+	return context.WithValue(ctx, testTraceKey{}, batch.MessageId), func(err error) {
+		t.record("end send")
+		t.mutex.Lock()
+		t.err = err
+		t.mutex.Unlock()
+	}
+}
+
+func (t *testTracer) StartUpload(req *http.Request, batch BatchInfo, attempt int) (*http.Request, func(int, error)) {
+	t.record("upload")
+	req.Header.Set("X-Trace", req.Context().Value(testTraceKey{}).(string))
+	return req, func(status int, err error) {
+		t.record("end upload")
+		t.mutex.Lock()
+		t.status = append(t.status, status)
+		t.mutex.Unlock()
+	}
+}
+
+func TestClientTracer(t *testing.T) {
+	var attempts int
+	var headers []string
+
+	tracer := &testTracer{}
+	errchan := make(chan error, 1)
+
+	client, _ := NewWithConfig("0123456789", Config{
+		Logger: testLogger{t.Logf, t.Logf},
+		Callback: testCallback{
+			func(m Message) { errchan <- nil },
+			func(m Message, e error) { errchan <- e },
+		},
+		Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			headers = append(headers, r.Header.Get("X-Trace"))
+
+			if attempts++; attempts == 1 {
+				return testTransportError.RoundTrip(r)
+			}
+			return testTransportOK.RoundTrip(r)
+		}),
+		BatchSize:  1,
+		RetryAfter: func(i int) time.Duration { return time.Millisecond },
+		Tracer:     tracer,
+		uid:        mockId,
+	})
+
+	client.Enqueue(Track{UserId: "A", Event: "B"})
+
+	if err := <-errchan; err != nil {
+		t.Fatal("sending the batch failed:", err)
+	}
+	client.Close()
+
+	if ref := []string{"send", "upload", "end upload", "upload", "end upload", "end send"}; !reflect.DeepEqual(tracer.events, ref) {
+		t.Errorf("invalid tracer events:\n- expected %q\n- found: %q", ref, tracer.events)
+	}
+
+	if b := tracer.batches[0]; b.MessageId != mockId() || b.Messages != 1 || b.Bytes == 0 {
+		t.Errorf("invalid batch info: %#v", b)
+	}
+
+	if !reflect.DeepEqual(tracer.status, []int{0, 200}) {
+		t.Error("invalid status codes reported to the tracer:", tracer.status)
+	}
+
+	if tracer.err != nil {
+		t.Error("invalid error reported at the end of the send:", tracer.err)
+	}
+
+	if !reflect.DeepEqual(headers, []string{mockId(), mockId()}) {
+		t.Error("requests modified by the tracer were not sent:", headers)
+	}
+}
@@ -1,6 +1,9 @@
 package analytics
 
-import "time"
+import (
+	"reflect"
+	"time"
+)
 
 // This type is used to represent traits in messages that support it.
 // It is a free-form object so the application can set any value it sees fit but
@@ -39,6 +42,10 @@ func (t Traits) SetBirthday(date time.Time) Traits {
 	return t.Set("birthday", date)
 }
 
+func (t Traits) SetCompany(company Company) Traits {
+	return t.Set("company", company)
+}
+
 func (t Traits) SetCreatedAt(date time.Time) Traits {
 	return t.Set("createdAt", date)
 }
@@ -55,14 +62,26 @@ func (t Traits) SetFirstName(firstName string) Traits {
 	return t.Set("firstName", firstName)
 }
 
+func (t Traits) SetEmployees(employees int) Traits {
+	return t.Set("employees", employees)
+}
+
 func (t Traits) SetGender(gender string) Traits {
 	return t.Set("gender", gender)
 }
 
+func (t Traits) SetIndustry(industry string) Traits {
+	return t.Set("industry", industry)
+}
+
 func (t Traits) SetLastName(lastName string) Traits {
 	return t.Set("lastName", lastName)
 }
 
+func (t Traits) SetMRR(mrr float64) Traits {
+	return t.Set("mrr", mrr)
+}
+
 func (t Traits) SetName(name string) Traits {
 	return t.Set("name", name)
 }
@@ -71,6 +90,16 @@ func (t Traits) SetPhone(phone string) Traits {
 	return t.Set("phone", phone)
 }
 
+func (t Traits) SetPlan(plan string) Traits {
+	return t.Set("plan", plan)
+}
+
+// Sets the address as an object, unlike `SetAddress` which sets it as a single
+// string.
+func (t Traits) SetPostalAddress(address Address) Traits {
+	return t.Set("address", address)
+}
+
 func (t Traits) SetTitle(title string) Traits {
 	return t.Set("title", title)
 }
@@ -87,3 +116,169 @@ func (t Traits) Set(field string, value interface{}) Traits {
 	t[field] = value
 	return t
 }
+
+// This type represents the `address` trait as defined in
+// https://segment.com/docs/connections/spec/identify/#traits
+type Address struct {
+	Street     string `json:"street,omitempty"`
+	City       string `json:"city,omitempty"`
+	State      string `json:"state,omitempty"`
+	PostalCode string `json:"postalCode,omitempty"`
+	Country    string `json:"country,omitempty"`
+}
+
+// This type represents the `company` trait as defined in
+// https://segment.com/docs/connections/spec/identify/#traits
+type Company struct {
+	Name          string `json:"name,omitempty"`
+	ID            string `json:"id,omitempty"`
+	Industry      string `json:"industry,omitempty"`
+	EmployeeCount int    `json:"employee_count,omitempty"`
+	Plan          string `json:"plan,omitempty"`
+}
+
+func (c Company) Validate() error {
+	if c.EmployeeCount < 0 {
+		return FieldError{
+			Type:  "analytics.Company",
+			Name:  "EmployeeCount",
+			Value: c.EmployeeCount,
+		}
+	}
+	return nil
+}
+
+// Validate returns an error if the address or company traits are malformed.
+// The traits may have been set with the typed setters or decoded from JSON, in
+// which case objects are represented as maps.
+//
+// Identify and group messages only validate the `Company` values set on their
+// traits, this method applies the stricter checks to the other shapes and can
+// be called by programs that want them.
+func (t Traits) Validate() error {
+	return t.validate("analytics.Traits")
+}
+
+// Returns an error if the typed values set on the traits are invalid.
+// Other values are not checked since messages with loosely typed traits have
+// always been accepted.
+func (t Traits) validateTyped() error {
+	switch v := t["company"].(type) {
+	case Company:
+		return v.Validate()
+	case *Company:
+		if v != nil {
+			return v.Validate()
+		}
+	}
+	return nil
+}
+
+// Returns an error if the address or company traits are malformed, typ is the
+// type of message reported in errors.
+func (t Traits) validate(typ string) error {
+	if v, ok := t["address"]; ok {
+		if err := validateTraitObject(typ, "address", v, []traitField{
+			{"street", true},
+			{"city", true},
+			{"state", true},
+			{"postalCode", true},
+			{"country", true},
+		}); err != nil {
+			return err
+		}
+	}
+
+	if v, ok := t["company"]; ok {
+		if err := validateTraitObject(typ, "company", v, []traitField{
+			{"name", true},
+			{"id", true},
+			{"industry", true},
+			{"employee_count", false},
+			{"plan", true},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// This type describes a known field of an object trait, fields are either
+// strings or non-negative numbers.
+type traitField struct {
+	name     string
+	isString bool
+}
+
+// Validates the value of an object trait, fields lists the known fields of the
+// object.
+func validateTraitObject(typ string, name string, value interface{}, fields []traitField) error {
+	switch v := value.(type) {
+	case Address, *Address:
+		if name == "address" {
+			return nil
+		}
+	case Company:
+		if name == "company" {
+			return v.Validate()
+		}
+	case *Company:
+		if name == "company" && v != nil {
+			return v.Validate()
+		}
+	case string:
+		// Addresses set with `SetAddress` are flat strings, which were
+		// supported before the address object was, and companies are often
+		// identified by their name only.
+		return nil
+	default:
+		// Objects decoded from JSON are maps, any map with string keys is
+		// accepted as long as the known fields have the expected types.
+		m := reflect.ValueOf(value)
+		if m.Kind() != reflect.Map || m.Type().Key().Kind() != reflect.String {
+			break
+		}
+
+		for _, f := range fields {
+			x := m.MapIndex(reflect.ValueOf(f.name).Convert(m.Type().Key()))
+			if x.IsValid() && !isTraitValue(x.Interface(), f.isString) {
+				return FieldError{
+					Type:  typ,
+					Name:  "Traits." + name + "." + f.name,
+					Value: x.Interface(),
+				}
+			}
+		}
+		return nil
+	}
+
+	return FieldError{
+		Type:  typ,
+		Name:  "Traits." + name,
+		Value: value,
+	}
+}
+
+func isTraitValue(value interface{}, isString bool) bool {
+	if value == nil {
+		// Unset fields are serialized as null, which is fine.
+		return true
+	}
+
+	if isString {
+		_, ok := value.(string)
+		return ok
+	}
+
+	switch n := reflect.ValueOf(value); n.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return n.Int() >= 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Float32, reflect.Float64:
+		return n.Float() >= 0
+	}
+
+	return false
+}
@@ -2,6 +2,7 @@ package analytics
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -19,14 +20,20 @@ func TestTraitsSimple(t *testing.T) {
 		"age":         {Traits{"age": number}, func(t Traits) { t.SetAge(number) }},
 		"avatar":      {Traits{"avatar": text}, func(t Traits) { t.SetAvatar(text) }},
 		"birthday":    {Traits{"birthday": date}, func(t Traits) { t.SetBirthday(date) }},
+		"company":     {Traits{"company": Company{Name: text}}, func(t Traits) { t.SetCompany(Company{Name: text}) }},
 		"createdAt":   {Traits{"createdAt": date}, func(t Traits) { t.SetCreatedAt(date) }},
 		"description": {Traits{"description": text}, func(t Traits) { t.SetDescription(text) }},
 		"email":       {Traits{"email": text}, func(t Traits) { t.SetEmail(text) }},
+		"employees":   {Traits{"employees": number}, func(t Traits) { t.SetEmployees(number) }},
 		"firstName":   {Traits{"firstName": text}, func(t Traits) { t.SetFirstName(text) }},
 		"lastName":    {Traits{"lastName": text}, func(t Traits) { t.SetLastName(text) }},
 		"gender":      {Traits{"gender": text}, func(t Traits) { t.SetGender(text) }},
+		"industry":    {Traits{"industry": text}, func(t Traits) { t.SetIndustry(text) }},
+		"mrr":         {Traits{"mrr": 0.5}, func(t Traits) { t.SetMRR(0.5) }},
 		"name":        {Traits{"name": text}, func(t Traits) { t.SetName(text) }},
 		"phone":       {Traits{"phone": text}, func(t Traits) { t.SetPhone(text) }},
+		"plan":        {Traits{"plan": text}, func(t Traits) { t.SetPlan(text) }},
+		"postal":      {Traits{"address": Address{City: text}}, func(t Traits) { t.SetPostalAddress(Address{City: text}) }},
 		"title":       {Traits{"title": text}, func(t Traits) { t.SetTitle(text) }},
 		"username":    {Traits{"username": text}, func(t Traits) { t.SetUsername(text) }},
 		"website":     {Traits{"website": text}, func(t Traits) { t.SetWebsite(text) }},
@@ -50,3 +57,87 @@ func TestTraitsMulti(t *testing.T) {
 		t.Errorf("invalid traits produced by chained setters:\n- expected %#v\n- found: %#v", t0, t1)
 	}
 }
+
+func TestTraitsValidate(t *testing.T) {
+	tests := []struct {
+		traits Traits
+		ref    error
+	}{
+		{NewTraits().SetAddress("1 Infinite Loop"), nil},
+		{NewTraits().SetPostalAddress(Address{City: "Cupertino"}), nil},
+		{NewTraits().SetCompany(Company{Name: "Acme", EmployeeCount: 10}), nil},
+		{Traits{"company": "Acme"}, nil},
+		{Traits{"address": map[string]interface{}{"city": "Cupertino", "floor": 2}}, nil},
+		{Traits{"address": map[string]string{"city": "Cupertino"}}, nil},
+		{Traits{"company": map[string]interface{}{"name": "Acme", "employee_count": 10.0}}, nil},
+		{Traits{"company": map[string]int{"employee_count": 10}}, nil},
+		{
+			NewTraits().SetCompany(Company{EmployeeCount: -1}),
+			FieldError{Type: "analytics.Company", Name: "EmployeeCount", Value: -1},
+		},
+		{
+			Traits{"address": 42},
+			FieldError{Type: "analytics.Traits", Name: "Traits.address", Value: 42},
+		},
+		{
+			Traits{"address": map[string]interface{}{"postalCode": 95014}},
+			FieldError{Type: "analytics.Traits", Name: "Traits.address.postalCode", Value: 95014},
+		},
+		{
+			Traits{"company": map[string]interface{}{"employee_count": "many"}},
+			FieldError{Type: "analytics.Traits", Name: "Traits.company.employee_count", Value: "many"},
+		},
+	}
+
+	for _, test := range tests {
+		if err := test.traits.Validate(); err != test.ref {
+			t.Errorf("%v: invalid validation error:\n- expected %v\n- found: %v", test.traits, test.ref, err)
+		}
+	}
+}
+
+func TestTraitsValidateMessage(t *testing.T) {
+	tests := []struct {
+		traits Traits
+		ref    error
+	}{
+		{Traits{"company": "Acme"}, nil},
+		{Traits{"address": 42}, nil},
+		{Traits{"company": map[string]string{"name": "Acme"}}, nil},
+		{Traits{"company": map[string]interface{}{"employee_count": "many"}}, nil},
+		{
+			NewTraits().SetCompany(Company{EmployeeCount: -1}),
+			FieldError{Type: "analytics.Company", Name: "EmployeeCount", Value: -1},
+		},
+	}
+
+	for _, test := range tests {
+		if err := (Identify{UserId: "1", Traits: test.traits}).Validate(); err != test.ref {
+			t.Errorf("%v: invalid identify validation error:\n- expected %v\n- found: %v", test.traits, test.ref, err)
+		}
+
+		if err := (Group{UserId: "1", GroupId: "2", Traits: test.traits}).Validate(); err != test.ref {
+			t.Errorf("%v: invalid group validation error:\n- expected %v\n- found: %v", test.traits, test.ref, err)
+		}
+	}
+}
+
+func TestTraitsStringCompanyEnqueue(t *testing.T) {
+	body, server := mockServer()
+	defer server.Close()
+
+	client, _ := NewWithConfig("h97jamjwbh", Config{
+		Endpoint:  server.URL,
+		Logger:    t,
+		BatchSize: 1,
+	})
+	defer client.Close()
+
+	if err := client.Enqueue(Identify{UserId: "1", Traits: Traits{"company": "Acme"}}); err != nil {
+		t.Fatal("enqueuing an identify message with a string company failed:", err)
+	}
+
+	if b := <-body; !strings.Contains(string(b), `"company": "Acme"`) {
+		t.Error("the string company was not sent:", string(b))
+	}
+}
@@ -13,6 +13,20 @@ func getString(msg FieldGetter, field string) string {
 	return ""
 }
 
+// Returns the traits of a generic message, or nil if the message has no traits
+// object.
+func getTraits(msg FieldGetter) Traits {
+	if val, ok := msg.GetField("traits"); ok {
+		switch traits := val.(type) {
+		case Traits:
+			return traits
+		case map[string]interface{}:
+			return traits
+		}
+	}
+	return nil
+}
+
 func ValidateFields(msg FieldGetter) error {
 	typ, _ := msg.GetField("type")
 	if str, ok := typ.(string); ok {
@@ -29,12 +43,14 @@ func ValidateFields(msg FieldGetter) error {
 				UserId:      getString(msg, "userId"),
 				AnonymousId: getString(msg, "anonymousId"),
 				GroupId:     getString(msg, "groupId"),
+				Traits:      getTraits(msg),
 			}.Validate()
 		case "identify":
 			return Identify{
 				Type:        "identify",
 				UserId:      getString(msg, "userId"),
 				AnonymousId: getString(msg, "anonymousId"),
+				Traits:      getTraits(msg),
 			}.Validate()
 		case "page":
 			return Page{
@@ -169,6 +169,55 @@ func TestValidateFieldsIdentifyInvalid(t *testing.T) {
 	}
 }
 
+func TestValidateFieldsIdentifyTraits(t *testing.T) {
+	msg := Event{
+		"type":   "identify",
+		"userId": "user123",
+		"traits": map[string]interface{}{
+			"address": map[string]interface{}{"city": "San Francisco", "postalCode": "94103"},
+			"company": map[string]interface{}{"name": "Segment", "employee_count": 100.0},
+		},
+	}
+	if err := msg.Validate(); err != nil {
+		t.Error("error returned when validating a generic identify message:", err)
+	}
+}
+
+func TestValidateFieldsTraitsLoose(t *testing.T) {
+	traits := map[string]interface{}{
+		"address": map[string]interface{}{"postalCode": 75001.0},
+		"company": 42.0,
+	}
+
+	// Generic messages must be validated with the same rules as the typed
+	// messages, which accept loosely typed traits.
+	tests := []struct {
+		msg   Event
+		typed Message
+	}{
+		{
+			Event{"type": "identify", "userId": "1", "traits": traits},
+			Identify{UserId: "1", Traits: traits},
+		},
+		{
+			Event{"type": "group", "userId": "1", "groupId": "2", "traits": traits},
+			Group{UserId: "1", GroupId: "2", Traits: traits},
+		},
+	}
+
+	for _, test := range tests {
+		err := test.msg.Validate()
+
+		if ref := test.typed.Validate(); err != ref {
+			t.Errorf("%v: generic and typed validation differ:\n- typed: %v\n- generic: %v", test.msg, ref, err)
+		}
+
+		if err != nil {
+			t.Errorf("%v: error returned when validating loosely typed traits: %v", test.msg, err)
+		}
+	}
+}
+
 func TestValidateFieldsPage(t *testing.T) {
 	msg := Event{
 		"type":   "page",
@@ -0,0 +1,298 @@
+package analytics
+
+import "time"
+
+// This file defines the events of the video spec as described in
+// https://segment.com/docs/connections/spec/video/
+//
+// Positions and lengths are expressed in seconds.
+
+// This type represents the properties of the playback events.
+type VideoPlayback struct {
+	SessionId       string
+	ContentAssetIds []string
+	ContentPodIds   []string
+	AdAssetId       string
+	AdPodId         string
+	AdType          string
+	Position        int
+	TotalLength     int
+	Bitrate         int
+	Framerate       float64
+	VideoPlayer     string
+	Sound           int
+	FullScreen      bool
+	AdEnabled       bool
+	Quality         string
+	Livestream      bool
+}
+
+// This type represents the properties of the Video Playback Started event.
+type VideoPlaybackStarted VideoPlayback
+
+// This type represents the properties of the Video Playback Paused event.
+type VideoPlaybackPaused VideoPlayback
+
+// This type represents the properties of the Video Playback Interrupted event.
+type VideoPlaybackInterrupted VideoPlayback
+
+// This type represents the properties of the Video Playback Completed event.
+type VideoPlaybackCompleted VideoPlayback
+
+// This type represents the properties of the content events.
+type VideoContent struct {
+	SessionId   string
+	AssetId     string
+	PodId       string
+	Program     string
+	Title       string
+	Description string
+	Season      string
+	Episode     string
+	Genre       string
+	Publisher   string
+	Channel     string
+	Keywords    []string
+	Airdate     time.Time
+	Position    int
+	TotalLength int
+	FullEpisode bool
+	Livestream  bool
+}
+
+// This type represents the properties of the Video Content Started event.
+type VideoContentStarted VideoContent
+
+// This type represents the properties of the Video Content Playing event, the
+// heartbeat sent periodically while content is playing.
+type VideoContentPlaying VideoContent
+
+// This type represents the properties of the Video Content Completed event.
+type VideoContentCompleted VideoContent
+
+// This type represents the properties of the ad events.
+type VideoAd struct {
+	SessionId   string
+	AssetId     string
+	PodId       string
+	Type        string
+	Title       string
+	Publisher   string
+	LoadType    string
+	Position    int
+	TotalLength int
+	Quartile    int
+}
+
+// This type represents the properties of the Video Ad Started event.
+type VideoAdStarted VideoAd
+
+// This type represents the properties of the Video Ad Playing event, the
+// heartbeat sent periodically while an ad is playing.
+type VideoAdPlaying VideoAd
+
+// This type represents the properties of the Video Ad Completed event.
+type VideoAdCompleted VideoAd
+
+func (e VideoPlaybackStarted) Validate() error {
+	return validateVideo("analytics.VideoPlaybackStarted", e.SessionId, e.Position, e.TotalLength)
+}
+
+func (e VideoPlaybackStarted) Apply(msg *Track) {
+	msg.Event = "Video Playback Started"
+	msg.Properties.setVideoPlayback(VideoPlayback(e))
+}
+
+func (e VideoPlaybackPaused) Validate() error {
+	return validateVideo("analytics.VideoPlaybackPaused", e.SessionId, e.Position, e.TotalLength)
+}
+
+func (e VideoPlaybackPaused) Apply(msg *Track) {
+	msg.Event = "Video Playback Paused"
+	msg.Properties.setVideoPlayback(VideoPlayback(e))
+}
+
+func (e VideoPlaybackInterrupted) Validate() error {
+	return validateVideo("analytics.VideoPlaybackInterrupted", e.SessionId, e.Position, e.TotalLength)
+}
+
+func (e VideoPlaybackInterrupted) Apply(msg *Track) {
+	msg.Event = "Video Playback Interrupted"
+	msg.Properties.setVideoPlayback(VideoPlayback(e))
+}
+
+func (e VideoPlaybackCompleted) Validate() error {
+	return validateVideo("analytics.VideoPlaybackCompleted", e.SessionId, e.Position, e.TotalLength)
+}
+
+func (e VideoPlaybackCompleted) Apply(msg *Track) {
+	msg.Event = "Video Playback Completed"
+	msg.Properties.setVideoPlayback(VideoPlayback(e))
+}
+
+func (e VideoContentStarted) Validate() error {
+	return validateVideo("analytics.VideoContentStarted", e.SessionId, e.Position, e.TotalLength)
+}
+
+func (e VideoContentStarted) Apply(msg *Track) {
+	msg.Event = "Video Content Started"
+	msg.Properties.setVideoContent(VideoContent(e))
+}
+
+func (e VideoContentPlaying) Validate() error {
+	return validateVideo("analytics.VideoContentPlaying", e.SessionId, e.Position, e.TotalLength)
+}
+
+func (e VideoContentPlaying) Apply(msg *Track) {
+	msg.Event = "Video Content Playing"
+	msg.Properties.setVideoContent(VideoContent(e))
+}
+
+func (e VideoContentCompleted) Validate() error {
+	return validateVideo("analytics.VideoContentCompleted", e.SessionId, e.Position, e.TotalLength)
+}
+
+func (e VideoContentCompleted) Apply(msg *Track) {
+	msg.Event = "Video Content Completed"
+	msg.Properties.setVideoContent(VideoContent(e))
+}
+
+func (e VideoAdStarted) Validate() error {
+	return validateVideo("analytics.VideoAdStarted", e.SessionId, e.Position, e.TotalLength)
+}
+
+func (e VideoAdStarted) Apply(msg *Track) {
+	msg.Event = "Video Ad Started"
+	msg.Properties.setVideoAd(VideoAd(e))
+}
+
+func (e VideoAdPlaying) Validate() error {
+	return validateVideo("analytics.VideoAdPlaying", e.SessionId, e.Position, e.TotalLength)
+}
+
+func (e VideoAdPlaying) Apply(msg *Track) {
+	msg.Event = "Video Ad Playing"
+	msg.Properties.setVideoAd(VideoAd(e))
+}
+
+func (e VideoAdCompleted) Validate() error {
+	return validateVideo("analytics.VideoAdCompleted", e.SessionId, e.Position, e.TotalLength)
+}
+
+func (e VideoAdCompleted) Apply(msg *Track) {
+	msg.Event = "Video Ad Completed"
+	msg.Properties.setVideoAd(VideoAd(e))
+}
+
+func validateVideo(typ string, sessionId string, position int, totalLength int) error {
+	if len(sessionId) == 0 {
+		return FieldError{
+			Type:  typ,
+			Name:  "SessionId",
+			Value: sessionId,
+		}
+	}
+
+	if position < 0 {
+		return FieldError{
+			Type:  typ,
+			Name:  "Position",
+			Value: position,
+		}
+	}
+
+	if totalLength < 0 {
+		return FieldError{
+			Type:  typ,
+			Name:  "TotalLength",
+			Value: totalLength,
+		}
+	}
+
+	return nil
+}
+
+// The spec requires the position and the livestream flag on all video events,
+// the total length is only set on videos that are not livestreams.
+func (p Properties) setVideoTimeline(position int, totalLength int, livestream bool) Properties {
+	p["position"] = position
+	p["livestream"] = livestream
+
+	if !livestream {
+		p.setInt("total_length", totalLength)
+	}
+
+	return p
+}
+
+func (p Properties) setStrings(name string, value []string) Properties {
+	if len(value) != 0 {
+		p[name] = value
+	}
+	return p
+}
+
+func (p Properties) setVideoPlayback(v VideoPlayback) Properties {
+	return p.
+		setString("session_id", v.SessionId).
+		setStrings("content_asset_ids", v.ContentAssetIds).
+		setStrings("content_pod_ids", v.ContentPodIds).
+		setString("ad_asset_id", v.AdAssetId).
+		setString("ad_pod_id", v.AdPodId).
+		setString("ad_type", v.AdType).
+		setInt("bitrate", v.Bitrate).
+		setFloat("framerate", v.Framerate).
+		setString("video_player", v.VideoPlayer).
+		setString("quality", v.Quality).
+		setVideoPlayerState(v.Sound, v.FullScreen, v.AdEnabled).
+		setVideoTimeline(v.Position, v.TotalLength, v.Livestream)
+}
+
+// The state of the player is always set on playback events, zero values are
+// meaningful: a sound of zero means the player is muted.
+func (p Properties) setVideoPlayerState(sound int, fullScreen bool, adEnabled bool) Properties {
+	p["sound"] = sound
+	p["full_screen"] = fullScreen
+	p["ad_enabled"] = adEnabled
+	return p
+}
+
+func (p Properties) setVideoContent(v VideoContent) Properties {
+	p.setString("session_id", v.SessionId).
+		setString("asset_id", v.AssetId).
+		setString("pod_id", v.PodId).
+		setString("program", v.Program).
+		setString("title", v.Title).
+		setString("description", v.Description).
+		setString("season", v.Season).
+		setString("episode", v.Episode).
+		setString("genre", v.Genre).
+		setString("publisher", v.Publisher).
+		setString("channel", v.Channel).
+		setStrings("keywords", v.Keywords).
+		setBool("full_episode", v.FullEpisode).
+		setVideoTimeline(v.Position, v.TotalLength, v.Livestream)
+
+	if !v.Airdate.IsZero() {
+		p["airdate"] = v.Airdate.Format(time.RFC3339)
+	}
+
+	return p
+}
+
+func (p Properties) setVideoAd(v VideoAd) Properties {
+	// Ads are never livestreams, the spec doesn't define the livestream flag
+	// on ad events.
+	p["position"] = v.Position
+
+	return p.
+		setString("session_id", v.SessionId).
+		setString("asset_id", v.AssetId).
+		setString("pod_id", v.PodId).
+		setString("type", v.Type).
+		setString("title", v.Title).
+		setString("publisher", v.Publisher).
+		setString("load_type", v.LoadType).
+		setInt("quartile", v.Quartile).
+		setInt("total_length", v.TotalLength)
+}
@@ -0,0 +1,98 @@
+package analytics
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestVideoEvents(t *testing.T) {
+	tests := []struct {
+		event SpecEvent
+		ref   string
+	}{
+		{
+			event: VideoPlaybackStarted{SessionId: "12345", ContentAssetIds: []string{"0129370"}, TotalLength: 392, VideoPlayer: "youtube", Sound: 88},
+			ref:   `{"event":"Video Playback Started","properties":{"ad_enabled":false,"content_asset_ids":["0129370"],"full_screen":false,"livestream":false,"position":0,"session_id":"12345","sound":88,"total_length":392,"video_player":"youtube"}}`,
+		},
+		{
+			event: VideoPlaybackPaused{SessionId: "12345", Position: 302, TotalLength: 392, FullScreen: true},
+			ref:   `{"event":"Video Playback Paused","properties":{"ad_enabled":false,"full_screen":true,"livestream":false,"position":302,"session_id":"12345","sound":0,"total_length":392}}`,
+		},
+		{
+			event: VideoPlaybackInterrupted{SessionId: "12345", Position: 10, TotalLength: 392, Livestream: true},
+			ref:   `{"event":"Video Playback Interrupted","properties":{"ad_enabled":false,"full_screen":false,"livestream":true,"position":10,"session_id":"12345","sound":0}}`,
+		},
+		{
+			event: VideoPlaybackCompleted{SessionId: "12345", Position: 392, TotalLength: 392},
+			ref:   `{"event":"Video Playback Completed","properties":{"ad_enabled":false,"full_screen":false,"livestream":false,"position":392,"session_id":"12345","sound":0,"total_length":392}}`,
+		},
+		{
+			event: VideoContentStarted{SessionId: "12345", AssetId: "0129370", Title: "Interview", Keywords: []string{"entertainment"}, Airdate: time.Date(1991, 8, 13, 0, 0, 0, 0, time.UTC), TotalLength: 1200, FullEpisode: true},
+			ref:   `{"event":"Video Content Started","properties":{"airdate":"1991-08-13T00:00:00Z","asset_id":"0129370","full_episode":true,"keywords":["entertainment"],"livestream":false,"position":0,"session_id":"12345","title":"Interview","total_length":1200}}`,
+		},
+		{
+			event: VideoContentPlaying{SessionId: "12345", AssetId: "0129370", Position: 10, TotalLength: 1200},
+			ref:   `{"event":"Video Content Playing","properties":{"asset_id":"0129370","livestream":false,"position":10,"session_id":"12345","total_length":1200}}`,
+		},
+		{
+			event: VideoContentCompleted{SessionId: "12345", AssetId: "0129370", Position: 1200, TotalLength: 1200},
+			ref:   `{"event":"Video Content Completed","properties":{"asset_id":"0129370","livestream":false,"position":1200,"session_id":"12345","total_length":1200}}`,
+		},
+		{
+			event: VideoAdStarted{SessionId: "12345", AssetId: "4311", PodId: "adRFBSD", Type: "pre-roll", TotalLength: 21},
+			ref:   `{"event":"Video Ad Started","properties":{"asset_id":"4311","pod_id":"adRFBSD","position":0,"session_id":"12345","total_length":21,"type":"pre-roll"}}`,
+		},
+		{
+			event: VideoAdPlaying{SessionId: "12345", AssetId: "4311", Position: 10, TotalLength: 21, Quartile: 1},
+			ref:   `{"event":"Video Ad Playing","properties":{"asset_id":"4311","position":10,"quartile":1,"session_id":"12345","total_length":21}}`,
+		},
+		{
+			event: VideoAdCompleted{SessionId: "12345", AssetId: "4311", Position: 21, TotalLength: 21},
+			ref:   `{"event":"Video Ad Completed","properties":{"asset_id":"4311","position":21,"session_id":"12345","total_length":21}}`,
+		},
+	}
+
+	for _, test := range tests {
+		track, err := SpecTrack(Track{}, test.event)
+		if err != nil {
+			t.Errorf("%T: %s", test.event, err)
+			continue
+		}
+
+		b, _ := json.Marshal(struct {
+			Event      string     `json:"event"`
+			Properties Properties `json:"properties"`
+		}{track.Event, track.Properties})
+
+		if s := string(b); s != test.ref {
+			t.Errorf("%T: invalid track message:\n- expected %s\n- found: %s", test.event, test.ref, s)
+		}
+	}
+}
+
+func TestVideoEventsValidate(t *testing.T) {
+	tests := []struct {
+		event SpecEvent
+		ref   FieldError
+	}{
+		{
+			event: VideoPlaybackStarted{},
+			ref:   FieldError{Type: "analytics.VideoPlaybackStarted", Name: "SessionId", Value: ""},
+		},
+		{
+			event: VideoContentPlaying{SessionId: "12345", Position: -1},
+			ref:   FieldError{Type: "analytics.VideoContentPlaying", Name: "Position", Value: -1},
+		},
+		{
+			event: VideoAdCompleted{SessionId: "12345", TotalLength: -1},
+			ref:   FieldError{Type: "analytics.VideoAdCompleted", Name: "TotalLength", Value: -1},
+		},
+	}
+
+	for _, test := range tests {
+		if err := test.event.Validate(); err != test.ref {
+			t.Errorf("%T: invalid validation error:\n- expected %v\n- found: %v", test.event, test.ref, err)
+		}
+	}
+}